@@ -0,0 +1,143 @@
+package benchmark
+
+import (
+	"testing"
+
+	bitbox "github.com/datagentleman/bitbox"
+)
+
+// Add encoded values as seed corpus.
+func addSeeds(f *testing.F, objects ...any) {
+	for _, obj := range objects {
+		buf := bitbox.NewBuffer(nil)
+		if err := bitbox.Encode(buf, obj); err != nil {
+			f.Fatalf("%v", err)
+		}
+		f.Add(buf.Data())
+	}
+	f.Add([]byte{})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0x01})
+}
+
+func FuzzDecodeTx(f *testing.F) {
+	in := makeTx()
+	addSeeds(f, &in, &tx{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		out := tx{}
+		bitbox.Decode(bitbox.NewBuffer(data), &out)
+
+		outs := []tx{}
+		bitbox.Decode(bitbox.NewBuffer(data), &outs)
+	})
+}
+
+func FuzzDecodeNested(f *testing.F) {
+	// Small values, big fixtures slow down every fuzzing run.
+	in := makeTx()
+	addSeeds(f,
+		[][]byte{{1, 2, 3}, {}},
+		[][]uint64{{1, 2}, {3}},
+		[][]tx{{in}, {}},
+		NamedType16{1, 2},
+		map[string][]uint16{"a": {1, 2}},
+	)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		outBytes := [][]byte{}
+		bitbox.Decode(bitbox.NewBuffer(data), &outBytes)
+
+		outU64 := [][]uint64{}
+		bitbox.Decode(bitbox.NewBuffer(data), &outU64)
+
+		outTx := [][]tx{}
+		bitbox.Decode(bitbox.NewBuffer(data), &outTx)
+
+		outArr := [4][32]byte{}
+		bitbox.Decode(bitbox.NewBuffer(data), &outArr)
+
+		outNamed := []NamedType16{}
+		bitbox.Decode(bitbox.NewBuffer(data), &outNamed)
+
+		outMap := map[string][]uint16{}
+		bitbox.Decode(bitbox.NewBuffer(data), &outMap)
+
+		outEmpty := []struct{}{}
+		bitbox.Decode(bitbox.NewBuffer(data), &outEmpty)
+
+		outEmptyMap := map[struct{}][0]uint64{}
+		bitbox.Decode(bitbox.NewBuffer(data), &outEmptyMap)
+	})
+}
+
+func FuzzDecodePOD(f *testing.F) {
+	pods := []aligned4Fields{{A: 1, B: 2, C: 3, D: 4}}
+	buf := bitbox.NewBuffer(nil)
+	bitbox.EncodePOD(buf, &pods)
+	f.Add(buf.Data())
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0x01})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		out := aligned4Fields{}
+		bitbox.DecodePOD(bitbox.NewBuffer(data), &out)
+
+		outs := []aligned4Fields{}
+		bitbox.DecodePOD(bitbox.NewBuffer(data), &outs)
+
+		outArr := [2]aligned4Fields{}
+		bitbox.DecodePOD(bitbox.NewBuffer(data), &outArr)
+	})
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add(uint64(42), uint64(21000), uint64(0), []byte{9, 8, 7, 6}, "access", uint8(0xff))
+	f.Add(uint64(0), uint64(0), uint64(0), []byte{}, "", uint8(0))
+
+	f.Fuzz(func(t *testing.T, nonce, gas, value uint64, data []byte, list string, mask uint8) {
+		in := tx{Nonce: nonce, Gas: gas, Data: data}
+
+		for i := 0; i+1 < len(list); i += 2 {
+			in.AccessList = append(in.AccessList, uint16(list[i])<<8|uint16(list[i+1]))
+		}
+
+		// Mask selects which pointers are set.
+		if mask&1 != 0 {
+			in.ChainID = &nonce
+		}
+		if mask&2 != 0 {
+			in.GasPrice = &gas
+		}
+		if mask&4 != 0 {
+			in.Value = &value
+		}
+		if mask&8 != 0 {
+			to := txNamedArray{}
+			copy(to[:], data)
+			in.To = &to
+		}
+
+		buf := bitbox.NewBuffer(nil)
+		err := bitbox.Encode(buf, &in, list, data)
+		bitbox.AssertEqual(t, nil, err)
+
+		out := tx{}
+		outList := ""
+		outData := []byte{}
+
+		err = bitbox.Decode(buf, &out, &outList, &outData)
+		bitbox.AssertEqual(t, nil, err)
+		bitbox.AssertEqual(t, 0, buf.Len())
+
+		// Empty and nil slices are encoded the same way.
+		if len(in.Data) == 0 {
+			in.Data = out.Data
+		}
+		if len(data) == 0 {
+			outData = data
+		}
+
+		bitbox.AssertEqual(t, in, out)
+		bitbox.AssertEqual(t, list, outList)
+		bitbox.AssertEqual(t, data, outData)
+	})
+}
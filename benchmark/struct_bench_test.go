@@ -38,6 +38,27 @@ func benchmarkStructBitbox(b *testing.B, in []aligned4Fields) {
 	}
 }
 
+// Packed layout, same bytes as binary.Write.
+func benchmarkStructPacked(b *testing.B, in []aligned4Fields) {
+	out := make([]aligned4Fields, len(in))
+	b.SetBytes(int64(aligned4FieldsSizeBytes * len(in) * 2))
+	b.ReportAllocs()
+
+	buf := bitbox.NewBuffer([]byte{})
+	buf.SetByteOrder(bitbox.BigEndian)
+
+	bitbox.EncodePacked(buf, in)
+	bitbox.DecodePacked(buf, out)
+	bitbox.AssertEqual(b, in, out)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Clear()
+		bitbox.EncodePacked(buf, in)
+		bitbox.DecodePacked(buf, out)
+	}
+}
+
 func benchmarkStructGob(b *testing.B, in []aligned4Fields) {
 	var out []aligned4Fields
 	b.SetBytes(int64(aligned4FieldsSizeBytes * len(in) * 2))
@@ -136,6 +157,10 @@ func BenchmarkEncodeDecodeStruct(b *testing.B) {
 		benchmarkStructBitbox(b, in)
 	})
 
+	b.Run("BitboxPacked", func(b *testing.B) {
+		benchmarkStructPacked(b, in)
+	})
+
 	b.Run("Gob", func(b *testing.B) {
 		benchmarkStructGob(b, in)
 	})
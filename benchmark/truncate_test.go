@@ -0,0 +1,110 @@
+package benchmark
+
+import (
+	"errors"
+	"testing"
+
+	bitbox "github.com/datagentleman/bitbox"
+)
+
+// Decode prefixes of encoded fixture and expect ErrOutOfBounds. Fixture is
+// truncated every step bytes, missing last byte is always checked.
+func runTruncated[T any](t *testing.T, name string, in T, step int) {
+	t.Helper()
+
+	t.Run(name, func(t *testing.T) {
+		buf := bitbox.NewBuffer(nil)
+		err := bitbox.Encode(buf, &in)
+		bitbox.AssertEqual(t, nil, err)
+
+		data := buf.Data()
+
+		truncate := func(i int) {
+			var out T
+
+			err := bitbox.Decode(bitbox.NewBuffer(data[:i]), &out)
+			if !errors.Is(err, bitbox.ErrOutOfBounds) {
+				t.Fatalf("truncated at %d/%d: expected ErrOutOfBounds, got %v", i, len(data), err)
+			}
+		}
+
+		for i := 0; i < len(data); i += step {
+			truncate(i)
+		}
+		truncate(len(data) - 1)
+
+		var out T
+		err = bitbox.Decode(bitbox.NewBuffer(data), &out)
+		bitbox.AssertEqual(t, nil, err)
+		bitbox.AssertEqual(t, in, out)
+	})
+}
+
+func TestTruncatedFixtures(t *testing.T) {
+	bytes128 := make([]byte, 128)
+	bytes4K := make([]byte, 4*1024)
+
+	inArray := NamedType17{}
+	for i := range inArray {
+		inArray[i] = byte(i)
+	}
+
+	// basic types
+	runTruncated(t, "slice_128B", bytes128, 1)
+	runTruncated(t, "slice_4KB", bytes4K, 1)
+	runTruncated(t, "bool", true, 1)
+	runTruncated(t, "string", "bitbox-benchmark", 1)
+	runTruncated(t, "int64", int64(-666), 1)
+	runTruncated(t, "complex128", complex128(complex(2.5, -3.5)), 1)
+
+	// named types and arrays
+	runTruncated(t, "named_int16", NamedType03(-4242), 1)
+	runTruncated(t, "named_complex64", NamedType12(complex(1.5, -2.5)), 1)
+	runTruncated(t, "named_string", NamedType14("bitbox-named"), 1)
+	runTruncated(t, "named_bytes", NamedType15{1, 2, 3, 4, 5, 6, 7, 8}, 1)
+	runTruncated(t, "named_uint64_slice", NamedType16{1, 2, 3, 4, 5, 6, 7, 8}, 1)
+	runTruncated(t, "named_byte_array", inArray, 1)
+	runTruncated(t, "named_uint32_array", NamedType18{1, 2, 3, 4}, 1)
+
+	// structs
+	runTruncated(t, "tx", makeTx(), 1)
+	runTruncated(t, "aligned_4_fields", aligned4Fields{A: 1, B: 2, C: 3, D: 4}, 1)
+
+	// nested
+	runTruncated(t, "array_100x32_byte", makeNestedArray100x32Byte(), 1)
+	runTruncated(t, "slice_2d_bytes", makeNested2DBytes(), 1)
+	runTruncated(t, "slice_2d_uint64", makeNested2DUint64(), 1)
+
+	// Fixtures of tx are too big to decode every prefix. Encoded tx
+	// takes 96 bytes, steps coprime with it still land on every offset
+	// within tx somewhere in fixture.
+	txStep, arrayStep := 13, 4999
+	if testing.Short() {
+		txStep, arrayStep = 97, 49999
+	}
+
+	runTruncated(t, "slice_2d_tx", makeNested2DTx(), txStep)
+	runTruncated(t, "array_100x100_tx", makeNestedArray100x100Tx(), arrayStep)
+}
+
+func TestTruncatedPOD(t *testing.T) {
+	in := make([]aligned4Fields, 100)
+	for i := range in {
+		in[i] = aligned4Fields{A: uint64(i), B: 2, C: 3, D: 4}
+	}
+
+	buf := bitbox.NewBuffer(nil)
+	err := bitbox.EncodePOD(buf, &in)
+	bitbox.AssertEqual(t, nil, err)
+
+	data := buf.Data()
+
+	for i := 0; i < len(data); i++ {
+		out := []aligned4Fields{}
+
+		err := bitbox.DecodePOD(bitbox.NewBuffer(data[:i]), &out)
+		if !errors.Is(err, bitbox.ErrOutOfBounds) {
+			t.Fatalf("truncated at %d/%d: expected ErrOutOfBounds, got %v", i, len(data), err)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package bitbox
+
+import (
+	"math/big"
+	"reflect"
+)
+
+var (
+	bigIntType   = reflect.TypeFor[big.Int]()
+	bigRatType   = reflect.TypeFor[big.Rat]()
+	bigFloatType = reflect.TypeFor[big.Float]()
+)
+
+// Forms of big.Float.
+const (
+	floatZero uint8 = iota
+	floatFinite
+	floatInf
+)
+
+// Encode big.Int as sign and magnitude bytes.
+func encodeBigInt(buf *Buffer, x *big.Int) {
+	sign := int8(x.Sign())
+	writeFixed(buf, &sign)
+	encodeMagnitude(buf, x)
+}
+
+// Encode absolute value of x as big-endian bytes with length prefix.
+func encodeMagnitude(buf *Buffer, x *big.Int) {
+	l := uint32((x.BitLen() + 7) / 8)
+	writeFixed(buf, &l)
+
+	if l > 0 {
+		buf.Write(x.Bytes())
+	}
+}
+
+// Decode big.Int. Sign must match magnitude.
+func decodeBigInt(buf *Buffer, x *big.Int) error {
+	sign := int8(0)
+
+	err := readFixed(buf, &sign)
+	if err != nil {
+		return err
+	}
+
+	err = decodeMagnitude(buf, x)
+	if err != nil {
+		return err
+	}
+
+	if sign < -1 || sign > 1 || (sign == 0) != (x.Sign() == 0) {
+		return invalidValue(reflect.ValueOf(sign))
+	}
+
+	if sign < 0 {
+		x.Neg(x)
+	}
+	return nil
+}
+
+// Decode big-endian bytes with length prefix into x.
+func decodeMagnitude(buf *Buffer, x *big.Int) error {
+	l, err := readCount(buf, bigIntType, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	data, err := buf.Next(l)
+	if err != nil {
+		return truncated(bigIntType, err)
+	}
+
+	x.SetBytes(data)
+	return nil
+}
+
+// Encode big.Rat as numerator and denominator magnitude.
+// Denominator is always positive.
+func encodeBigRat(buf *Buffer, x *big.Rat) {
+	encodeBigInt(buf, x.Num())
+	encodeMagnitude(buf, x.Denom())
+}
+
+// Decode big.Rat. Denominator can't be zero.
+func decodeBigRat(buf *Buffer, x *big.Rat) error {
+	num := new(big.Int)
+	denom := new(big.Int)
+
+	err := decodeBigInt(buf, num)
+	if err != nil {
+		return err
+	}
+
+	err = decodeMagnitude(buf, denom)
+	if err != nil {
+		return err
+	}
+
+	if denom.Sign() == 0 {
+		return invalidValue(reflect.ValueOf(denom))
+	}
+
+	x.SetFrac(num, denom)
+	return nil
+}
+
+// Encode big.Float as precision, rounding mode, form and sign.
+// Finite numbers are followed by exponent and mantissa bytes,
+// value is mantissa * 2**(exponent - mantissa bits).
+func encodeBigFloat(buf *Buffer, x *big.Float) {
+	prec := uint32(x.Prec())
+	mode := uint8(x.Mode())
+	neg := x.Signbit()
+	form := floatFinite
+
+	switch {
+	case x.IsInf():
+		form = floatInf
+	case x.Sign() == 0:
+		form = floatZero
+	}
+
+	writeFixed(buf, &prec)
+	writeFixed(buf, &mode)
+	writeFixed(buf, &form)
+	writeFixed(buf, &neg)
+
+	if form != floatFinite {
+		return
+	}
+
+	// Mantissa is in [0.5, 1), shifting it by its minimal precision
+	// gives integer without losing any bits.
+	mant := new(big.Float)
+	exp := int32(x.MantExp(mant))
+
+	mant.SetMantExp(mant, int(x.MinPrec()))
+	i, _ := mant.Int(nil)
+
+	writeFixed(buf, &exp)
+	encodeMagnitude(buf, i)
+}
+
+// Decode big.Float.
+func decodeBigFloat(buf *Buffer, x *big.Float) error {
+	prec := uint32(0)
+	mode := uint8(0)
+	form := uint8(0)
+	neg := false
+
+	err := readFixed(buf, &prec)
+	if err != nil {
+		return err
+	}
+
+	err = readFixed(buf, &mode)
+	if err != nil {
+		return err
+	}
+
+	err = readFixed(buf, &form)
+	if err != nil {
+		return err
+	}
+
+	err = readFixed(buf, &neg)
+	if err != nil {
+		return err
+	}
+
+	if big.RoundingMode(mode) > big.ToPositiveInf {
+		return invalidValue(reflect.ValueOf(big.RoundingMode(mode)))
+	}
+
+	*x = big.Float{}
+	x.SetPrec(uint(prec)).SetMode(big.RoundingMode(mode))
+
+	switch form {
+	case floatZero:
+	case floatInf:
+		x.SetInf(neg)
+		return nil
+	case floatFinite:
+		exp := int32(0)
+		mant := new(big.Int)
+
+		err = readFixed(buf, &exp)
+		if err != nil {
+			return err
+		}
+
+		err = decodeMagnitude(buf, mant)
+		if err != nil {
+			return err
+		}
+
+		x.SetInt(mant)
+		x.SetMantExp(x, -mant.BitLen())
+		x.SetMantExp(x, int(exp))
+	default:
+		return invalidValue(reflect.ValueOf(form))
+	}
+
+	if neg {
+		x.Neg(x)
+	}
+	return nil
+}
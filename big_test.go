@@ -0,0 +1,180 @@
+package bitbox
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func bigInt(s string) *big.Int {
+	i, _ := new(big.Int).SetString(s, 10)
+	return i
+}
+
+func bigRat(s string) *big.Rat {
+	r, _ := new(big.Rat).SetString(s)
+	return r
+}
+
+func bigFloat(s string, prec uint, mode big.RoundingMode) *big.Float {
+	f, _, _ := big.ParseFloat(s, 10, prec, mode)
+	return f
+}
+
+func roundTrip(t *testing.T, in, out any) {
+	t.Helper()
+
+	buf := NewBuffer(nil)
+	err := Encode(buf, in)
+	AssertEqual(t, nil, err)
+	err = Decode(buf, out)
+	AssertEqual(t, nil, err)
+	AssertEqual(t, 0, buf.Len())
+}
+
+func TestBigInt(t *testing.T) {
+	cases := []string{
+		"0",
+		"1",
+		"-1",
+		"255",
+		"-256",
+		"123456789012345678901234567890123456789012345678901234567890",
+		"-" + new(big.Int).Lsh(big.NewInt(1), 4096).String(),
+	}
+
+	for _, c := range cases {
+		t.Run(c[:min(len(c), 20)], func(t *testing.T) {
+			in := bigInt(c)
+			out := big.NewInt(42)
+
+			roundTrip(t, in, out)
+			AssertEqual(t, 0, in.Cmp(out))
+
+			// Value and pointer to pointer.
+			var ptr *big.Int
+			roundTrip(t, *in, &ptr)
+			AssertEqual(t, 0, in.Cmp(ptr))
+		})
+	}
+
+	t.Run("zero_value", func(t *testing.T) {
+		out := big.NewInt(7)
+		roundTrip(t, new(big.Int), out)
+		AssertEqual(t, 0, out.Sign())
+	})
+}
+
+func TestBigRat(t *testing.T) {
+	cases := []string{"0", "1/3", "-22/7", "123456789012345678901234567890/7", "-1e-40"}
+
+	for _, c := range cases {
+		t.Run(c, func(t *testing.T) {
+			in := bigRat(c)
+			out := big.NewRat(5, 9)
+
+			roundTrip(t, in, out)
+			AssertEqual(t, 0, in.Cmp(out))
+		})
+	}
+
+	t.Run("zero_value", func(t *testing.T) {
+		out := big.NewRat(5, 9)
+		roundTrip(t, new(big.Rat), out)
+		AssertEqual(t, 0, out.Sign())
+	})
+}
+
+func TestBigFloat(t *testing.T) {
+	cases := []struct {
+		name string
+		in   *big.Float
+	}{
+		{"zero", new(big.Float)},
+		{"zero_prec", new(big.Float).SetPrec(200)},
+		{"neg_zero", new(big.Float).Neg(new(big.Float).SetFloat64(0))},
+		{"float64", big.NewFloat(-3.141592653589793)},
+		{"tiny", big.NewFloat(math.SmallestNonzeroFloat64)},
+		{"huge", bigFloat("1.5e100000", 500, big.ToZero)},
+		{"precise", bigFloat("-0.1234567890123456789012345678901234567890", 300, big.AwayFromZero)},
+		{"inf", new(big.Float).SetInf(false)},
+		{"neg_inf", new(big.Float).SetPrec(20).SetInf(true)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out := big.NewFloat(1)
+
+			roundTrip(t, c.in, out)
+			AssertEqual(t, 0, c.in.Cmp(out))
+			AssertEqual(t, c.in.Prec(), out.Prec())
+			AssertEqual(t, c.in.Mode(), out.Mode())
+			AssertEqual(t, c.in.Signbit(), out.Signbit())
+			AssertEqual(t, c.in.Text('g', -1), out.Text('g', -1))
+		})
+	}
+}
+
+func TestBigStruct(t *testing.T) {
+	type account struct {
+		Balance big.Int
+		Limit   *big.Int
+		Rate    *big.Rat
+		Price   big.Float
+		Fees    []big.Int
+	}
+
+	in := account{
+		Balance: *bigInt("-98765432109876543210"),
+		Limit:   bigInt("100000000000000000000000"),
+		Rate:    bigRat("3/1000"),
+		Price:   *big.NewFloat(12.5),
+		Fees:    []big.Int{*big.NewInt(1), *big.NewInt(-2), {}},
+	}
+	out := account{}
+
+	roundTrip(t, &in, &out)
+
+	AssertEqual(t, 0, in.Balance.Cmp(&out.Balance))
+	AssertEqual(t, 0, in.Limit.Cmp(out.Limit))
+	AssertEqual(t, 0, in.Rate.Cmp(out.Rate))
+	AssertEqual(t, 0, in.Price.Cmp(&out.Price))
+	AssertEqual(t, len(in.Fees), len(out.Fees))
+
+	for i := range in.Fees {
+		AssertEqual(t, 0, in.Fees[i].Cmp(&out.Fees[i]))
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		in := account{}
+		out := account{Limit: big.NewInt(1)}
+
+		roundTrip(t, &in, &out)
+		Assert(t, true, out.Limit == nil)
+	})
+}
+
+func TestBigInvalid(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		out  any
+		err  error
+	}{
+		{"bad_sign", []byte{2, 1, 0, 0, 0, 1}, new(big.Int), ErrInvalidValue},
+		{"zero_sign_with_value", []byte{0, 1, 0, 0, 0, 1}, new(big.Int), ErrInvalidValue},
+		{"short_magnitude", []byte{1, 9, 0, 0, 0, 1}, new(big.Int), ErrOutOfBounds},
+		{"zero_denominator", []byte{1, 1, 0, 0, 0, 1, 0, 0, 0, 0}, new(big.Rat), ErrInvalidValue},
+		{"bad_mode", []byte{53, 0, 0, 0, 9, 0, 0}, new(big.Float), ErrInvalidValue},
+		{"bad_form", []byte{53, 0, 0, 0, 0, 7, 0}, new(big.Float), ErrInvalidValue},
+		{"truncated_float", []byte{53, 0, 0}, new(big.Float), ErrOutOfBounds},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := Decode(NewBuffer(c.data), c.out)
+			Assert(t, true, errors.Is(err, c.err))
+		})
+	}
+}
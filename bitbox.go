@@ -4,13 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"unsafe"
 )
 
 var (
-	ErrUnknownType  = errors.New("bitbox: unknown type")
-	ErrInvalidValue = errors.New("bitbox: invalid value")
-	ErrOutOfBounds  = errors.New("bitbox: out of bounds")
+	ErrUnknownType   = errors.New("bitbox: unknown type")
+	ErrInvalidValue  = errors.New("bitbox: invalid value")
+	ErrOutOfBounds   = errors.New("bitbox: out of bounds")
+	ErrOverflow      = errors.New("bitbox: value overflows type")
+	ErrLimitExceeded = errors.New("bitbox: decode limit exceeded")
+	ErrInvalidTag    = errors.New("bitbox: invalid struct tag")
+	ErrNotPOD        = errors.New("bitbox: type is not POD")
 )
 
 func unknownType(t reflect.Type) error {
@@ -20,17 +25,87 @@ func unknownType(t reflect.Type) error {
 	return fmt.Errorf("%w: %s", ErrUnknownType, t.String())
 }
 
+// Type ID not found in registry.
+func unknownTypeID(id uint32) error {
+	return fmt.Errorf("%w: id=%d", ErrUnknownType, id)
+}
+
+// Registered type can't be stored in interface.
+func notAssignable(t reflect.Type, iface reflect.Type) error {
+	return fmt.Errorf("%w: %s doesn't implement %s", ErrInvalidValue, t.String(), iface.String())
+}
+
 func outOfBounds(want int, have int) error {
 	return fmt.Errorf("%w: requested=%d available=%d", ErrOutOfBounds, want, have)
 }
 
+// Not enough data to read value of given type.
+func shortRead(t reflect.Type, want int, have int) error {
+	return fmt.Errorf("%w: requested=%d available=%d type=%s", ErrOutOfBounds, want, have, t.String())
+}
+
+// Add type context to error from Next.
+func truncated(t reflect.Type, err error) error {
+	return fmt.Errorf("%w type=%s", err, t.String())
+}
+
+// Add struct field context to error.
+func fieldError(t reflect.Type, i int, err error) error {
+	return fmt.Errorf("%s.%s: %w", t.String(), t.Field(i).Name, err)
+}
+
+// Struct field can't be copied as memory.
+func notPOD(t reflect.Type, i int) error {
+	f := t.Field(i)
+	return fmt.Errorf("%w: %s.%s has type %s", ErrNotPOD, t.String(), f.Name, f.Type.String())
+}
+
+// Type can't be encoded in packed layout.
+func noFixedSize(t reflect.Type) error {
+	return fmt.Errorf("%w: %s has no fixed size", ErrNotPOD, t.String())
+}
+
+// Elements of slice or map were encoded as zero bytes.
+func noData(t reflect.Type) error {
+	return fmt.Errorf("%w: elements of %s have no encoded data", ErrInvalidValue, t.String())
+}
+
+// Different map keys have the same encoding.
+func duplicateKey(t reflect.Type) error {
+	return fmt.Errorf("%w: keys of %s have the same encoding", ErrInvalidValue, t.String())
+}
+
+// Custom encoder wrote no data.
+func emptyEncoding(t reflect.Type) error {
+	return fmt.Errorf("%w: %s.EncodeBitbox wrote no data", ErrInvalidValue, t.String())
+}
+
+func overflow(t reflect.Type, v any) error {
+	return fmt.Errorf("%w: value=%v type=%s", ErrOverflow, v, t.String())
+}
+
+// Check if 64 bit value doesn't fit int with given number of bits.
+func intOverflows(v int64, bits int) bool {
+	if bits >= 64 {
+		return false
+	}
+
+	limit := int64(1) << (bits - 1)
+	return v < -limit || v >= limit
+}
+
+// Check if 64 bit value doesn't fit uint with given number of bits.
+func uintOverflows(v uint64, bits int) bool {
+	return bits < 64 && v >= uint64(1)<<bits
+}
+
 func invalidValue(val reflect.Value) error {
 	return fmt.Errorf("%w: %s", ErrInvalidValue, val.String())
 }
 
 // Get pointer to fixed type (including structs) and cast it to []byte.
-// When passing structs, make sure they are memory aligned otherwise
-// you would have extra bytes.
+// Bytes of structs include their padding as it is in memory,
+// EncodePOD writes padding as zeros.
 func ToBytes[T any](obj *T) []byte {
 	size := unsafe.Sizeof(*obj)
 	return unsafe.Slice((*byte)(unsafe.Pointer(obj)), size)
@@ -68,6 +143,23 @@ func isFixedType(kind reflect.Kind) bool {
 	}
 }
 
+// Detect if elements of given type can be copied directly as memory.
+// Named types with custom encoding are never copied. Int and uint are
+// always encoded as 64 bit values, so they can be copied only on 64 bit
+// platforms.
+func isCopyable(typ reflect.Type) bool {
+	kind := typ.Kind()
+
+	if isFixedType(kind) && isMarshaler(typ) {
+		return false
+	}
+
+	if kind == reflect.Int || kind == reflect.Uint {
+		return strconv.IntSize == 64
+	}
+	return isFixedType(kind)
+}
+
 func isStruct(kind reflect.Kind) bool {
 	return kind == reflect.Struct
 }
@@ -93,10 +185,22 @@ func addressable(val reflect.Value) reflect.Value {
 	return tmp
 }
 
+// Make unexported field of addressable struct readable and settable.
+func exposed(field reflect.Value) reflect.Value {
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+}
+
+// Get pointer to memory of value, copying it when needed.
+func valuePointer(val reflect.Value) unsafe.Pointer {
+	return unsafe.Pointer(addressable(val).UnsafeAddr())
+}
+
 // Ensure that slice has enough space for given number of elements.
 func ensureLen(val reflect.Value, num int) {
+	// Grow doesn't allocate new slice header, unlike MakeSlice.
 	if val.Cap() < num {
-		val.Set(MakeSlice(val.Type(), num))
+		val.SetLen(0)
+		val.Grow(num)
 	}
 
 	val.SetLen(num)
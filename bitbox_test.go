@@ -1,7 +1,15 @@
 package bitbox
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"unsafe"
 )
 
 type alignedStruct struct {
@@ -11,11 +19,13 @@ type alignedStruct struct {
 	D float32
 }
 
+type NamedTypeInt1 int
 type NamedTypeInt2 int8
 type NamedTypeInt3 int16
 type NamedTypeInt4 int32
 type NamedTypeInt5 int64
 
+type NamedTypeUint1 uint
 type NamedTypeUint2 uint8
 type NamedTypeUint3 uint16
 type NamedTypeUint4 uint32
@@ -36,6 +46,8 @@ type NamedTypeSlice1 []uint16
 type NamedTypeArray1 [4]uint16
 type NamedTypeArray2 [32]uint8
 
+type NamedTypeMap1 map[string]uint64
+
 type Tx struct {
 	ChainID    *uint64
 	Nonce      uint64
@@ -97,12 +109,14 @@ func TestFixedTypes(t *testing.T) {
 		run  func(*testing.T)
 	}{
 		// int
+		{name: "int", run: func(t *testing.T) { runTest(t, "int", int(-1337)) }},
 		{name: "int8", run: func(t *testing.T) { runTest(t, "int8", int8(-8)) }},
 		{name: "int16", run: func(t *testing.T) { runTest(t, "int16", int16(-16)) }},
 		{name: "int32", run: func(t *testing.T) { runTest(t, "int32", int32(-32)) }},
 		{name: "int64", run: func(t *testing.T) { runTest(t, "int64", int64(-64)) }},
 
 		// uint
+		{name: "uint", run: func(t *testing.T) { runTest(t, "uint", uint(1337)) }},
 		{name: "uint8", run: func(t *testing.T) { runTest(t, "uint8", uint8(8)) }},
 		{name: "uint16", run: func(t *testing.T) { runTest(t, "uint16", uint16(16)) }},
 		{name: "uint32", run: func(t *testing.T) { runTest(t, "uint32", uint32(32)) }},
@@ -134,6 +148,243 @@ func TestSlices(t *testing.T) {
 	runTest(t, "slice_slice_slice_uint64", [][][]uint64{{{1, 2}, nil, {3, 4, 5}}, nil, {{6}, {7, 8, 9}}})
 }
 
+func TestIntUint(t *testing.T) {
+	runTest(t, "slice_int", []int{-1, 0, 1, 1 << 30})
+	runTest(t, "slice_uint", []uint{0, 1, 1 << 31})
+	runTest(t, "slice_slice_int", [][]int{{-1, 2}, nil, {3}})
+	runTest(t, "array_int", [3]int{-5, 0, 5})
+	runTest(t, "array_uint", [2][2]uint{{1, 2}, {3, 4}})
+	runTest(t, "map_int_uint", map[int]uint{-1: 1, 2: 2})
+
+	type counter struct {
+		Name  string
+		Count int
+		Total uint
+	}
+	runTest(t, "struct_int", counter{Name: "hits", Count: -42, Total: 42})
+
+	t.Run("wire_width", func(t *testing.T) {
+		buf := NewBuffer(nil)
+		err := Encode(buf, int(-1), uint(1), NamedTypeInt1(2))
+		AssertEqual(t, nil, err)
+
+		Assert(t, 24, buf.Len())
+	})
+
+	t.Run("overflow_range", func(t *testing.T) {
+		Assert(t, false, intOverflows(math.MaxInt32, 32))
+		Assert(t, false, intOverflows(math.MinInt32, 32))
+		Assert(t, true, intOverflows(math.MaxInt32+1, 32))
+		Assert(t, true, intOverflows(math.MinInt32-1, 32))
+		Assert(t, true, intOverflows(int64(1)<<40, 32))
+		Assert(t, false, intOverflows(math.MaxInt64, 64))
+		Assert(t, false, intOverflows(math.MinInt64, 64))
+
+		Assert(t, false, uintOverflows(math.MaxUint32, 32))
+		Assert(t, true, uintOverflows(math.MaxUint32+1, 32))
+		Assert(t, false, uintOverflows(math.MaxUint64, 64))
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		if strconv.IntSize == 64 {
+			t.Skip("int fits 64 bit values")
+		}
+
+		buf := NewBuffer(nil)
+		err := Encode(buf, int64(1)<<40)
+		AssertEqual(t, nil, err)
+
+		var out int
+		err = Decode(buf, &out)
+		Assert(t, true, errors.Is(err, ErrOverflow))
+	})
+}
+
+func TestMaps(t *testing.T) {
+	// fast path
+	runTest(t, "map_string_string", map[string]string{"a": "1", "b": "22", "": "empty"})
+	runTest(t, "map_string_uint64", map[string]uint64{"one": 1, "two": 2})
+	runTest(t, "map_uint64_bytes", map[uint64][]byte{1: {1, 2, 3}, 2: nil, 3: {4}})
+
+	// slow path - reflections
+	runTest(t, "map_int32_float64", map[int32]float64{-1: 1.5, 7: -2.25})
+	runTest(t, "map_string_slice", map[string][]uint16{"a": {1, 2}, "b": {3}})
+	runTest(t, "map_array_struct", map[[2]uint8]alignedStruct{{1, 2}: {A: 1, B: 2, C: 3, D: 4}})
+	runTest(t, "map_nested", map[string]map[uint32]string{"x": {1: "one"}, "y": {2: "two"}})
+	runTest(t, "slice_map", []map[string]string{{"a": "b"}, {"c": "d"}})
+
+	type withMap struct {
+		Name     string
+		Balances map[string]uint64
+	}
+	runTest(t, "struct_with_map", withMap{Name: "acc", Balances: map[string]uint64{"eth": 10, "btc": 2}})
+
+	runEncoderDecoder(t, "named_map", NamedTypeMap1{"a": 1, "b": 2})
+
+	t.Run("reuse_existing_map", func(t *testing.T) {
+		in := map[string]string{"a": "1"}
+		out := map[string]string{"stale": "value"}
+
+		buf := NewBuffer(nil)
+		err := Encode(buf, in)
+		AssertEqual(t, nil, err)
+		err = Decode(buf, &out)
+		AssertEqual(t, nil, err)
+
+		AssertEqual(t, in, out)
+	})
+}
+
+func TestDeterministicMaps(t *testing.T) {
+	encodeBytes := func(t *testing.T, obj any) []byte {
+		t.Helper()
+
+		buf := NewBuffer(nil)
+		buf.SetDeterministic(true)
+		err := buf.Encode(obj)
+		AssertEqual(t, nil, err)
+
+		return buf.Data()
+	}
+
+	strs := map[string]string{}
+	nums := map[uint32]int64{}
+	nested := map[string]map[uint64][]byte{}
+
+	for i := 0; i < 100; i++ {
+		key := string(rune('a'+i%26)) + string(rune('A'+i/26))
+		strs[key] = key + key
+		nums[uint32(i*7919)] = int64(-i)
+		nested[key] = map[uint64][]byte{uint64(i): {byte(i)}, uint64(i + 1000): {1, 2}}
+	}
+
+	cases := []struct {
+		name string
+		obj  any
+	}{
+		{name: "fast_path", obj: strs},
+		{name: "reflections", obj: nums},
+		{name: "nested", obj: nested},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			expected := encodeBytes(t, tc.obj)
+
+			for i := 0; i < 50; i++ {
+				actual := encodeBytes(t, tc.obj)
+				Assert(t, true, bytes.Equal(expected, actual))
+			}
+		})
+	}
+
+	t.Run("round_trip", func(t *testing.T) {
+		out := map[string]map[uint64][]byte{}
+
+		buf := NewBuffer(encodeBytes(t, nested))
+		err := buf.Decode(&out)
+		AssertEqual(t, nil, err)
+
+		AssertEqual(t, nested, out)
+	})
+
+	t.Run("fast_path_matches_reflections", func(t *testing.T) {
+		type namedMap map[string]string
+
+		Assert(t, true, bytes.Equal(encodeBytes(t, strs), encodeBytes(t, namedMap(strs))))
+	})
+
+	t.Run("duplicate_keys", func(t *testing.T) {
+		type key struct {
+			ID    uint32
+			Cache string `bitbox:"-"`
+		}
+
+		maps := []any{
+			map[float64]uint8{math.NaN(): 1, math.NaN(): 2},
+			map[key]uint8{{ID: 1, Cache: "a"}: 1, {ID: 1, Cache: "b"}: 2},
+		}
+
+		for _, m := range maps {
+			buf := NewBuffer(nil)
+			buf.SetDeterministic(true)
+
+			err := buf.Encode(m)
+			Assert(t, true, errors.Is(err, ErrInvalidValue))
+		}
+	})
+}
+
+func TestByteOrder(t *testing.T) {
+	orders := []struct {
+		name  string
+		order ByteOrder
+		std   binary.ByteOrder
+	}{
+		{name: "little", order: LittleEndian, std: binary.LittleEndian},
+		{name: "big", order: BigEndian, std: binary.BigEndian},
+	}
+
+	for _, o := range orders {
+		t.Run(o.name+"/wire", func(t *testing.T) {
+			nums := []uint64{1, 2, 0x0102030405060708}
+			pod := alignedStruct{A: 1, B: 2, C: 3, D: 4.5}
+
+			buf := NewBuffer(nil)
+			buf.SetByteOrder(o.order)
+			err := buf.Encode(uint32(0x01020304), nums, "ab", complex64(complex(1, 2)), int(-2))
+			AssertEqual(t, nil, err)
+			err = buf.EncodePOD(&pod)
+			AssertEqual(t, nil, err)
+
+			expected := bytes.NewBuffer(nil)
+			binary.Write(expected, o.std, uint32(0x01020304))
+			binary.Write(expected, o.std, uint32(len(nums)))
+			binary.Write(expected, o.std, nums)
+			binary.Write(expected, o.std, uint32(2))
+			expected.WriteString("ab")
+			binary.Write(expected, o.std, complex64(complex(1, 2)))
+			binary.Write(expected, o.std, int64(-2))
+			binary.Write(expected, o.std, pod)
+
+			Assert(t, true, bytes.Equal(expected.Bytes(), buf.Data()))
+		})
+
+		t.Run(o.name+"/round_trip", func(t *testing.T) {
+			chainID := uint64(11155111)
+			to := NamedTypeArray2{1, 2, 3}
+
+			in := Tx{ChainID: &chainID, Nonce: 42, Gas: 21000, To: &to, Data: []byte{1, 2}, AccessList: NamedTypeSlice1{1, 2}}
+			pods := []alignedStruct{{A: 1, B: 2, C: 3, D: 4}, {A: 5, B: 6, C: 7, D: 8}}
+			arr := [2][3]float64{{1.5, 2.5, 3.5}, {-1, -2, -3}}
+			m := map[string]uint64{"a": 1, "b": 2}
+
+			out := Tx{}
+			outPods := []alignedStruct{}
+			outArr := [2][3]float64{}
+			outMap := map[string]uint64{}
+
+			buf := NewBuffer(nil)
+			buf.SetByteOrder(o.order)
+
+			err := buf.Encode(&in, &arr, m)
+			AssertEqual(t, nil, err)
+			err = buf.EncodePOD(&pods)
+			AssertEqual(t, nil, err)
+
+			err = buf.Decode(&out, &outArr, &outMap)
+			AssertEqual(t, nil, err)
+			err = buf.DecodePOD(&outPods)
+			AssertEqual(t, nil, err)
+
+			AssertEqual(t, in, out)
+			AssertEqual(t, arr, outArr)
+			AssertEqual(t, m, outMap)
+			AssertEqual(t, pods, outPods)
+		})
+	}
+}
+
 func TestArrays(t *testing.T) {
 	runTest(t, "array", [4]uint16{1, 2, 3, 4})
 
@@ -216,14 +467,335 @@ func TestStruct(t *testing.T) {
 	})
 }
 
+func TestPointers(t *testing.T) {
+	type nested struct {
+		Value **uint64
+		List  []*string
+	}
+
+	one := uint64(1)
+	two := uint64(2)
+	pOne := &one
+	hello := "hello"
+
+	tx := Tx{Nonce: 1, ChainID: &one}
+	tx2 := Tx{Nonce: 2, Value: &two}
+
+	runTest(t, "slice", []*Tx{&tx, nil, &tx2})
+	runTest(t, "array", [4]*uint64{&one, nil, &two, nil})
+	runTest(t, "map", map[string]*uint64{"one": &one, "nil": nil})
+	runTest(t, "nested", []**uint64{&pOne, nil, new(*uint64)})
+	runTest(t, "struct", nested{Value: &pOne, List: []*string{&hello, nil}})
+	runTest(t, "slice_pointer", []*[]uint16{{1, 2}, nil})
+
+	t.Run("top_level", func(t *testing.T) {
+		var out *Tx
+
+		buf := NewBuffer(nil)
+		err := Encode(buf, &tx)
+		AssertEqual(t, nil, err)
+		err = Decode(buf, &out)
+		AssertEqual(t, nil, err)
+
+		AssertEqual(t, tx, *out)
+	})
+
+	t.Run("struct_compatible", func(t *testing.T) {
+		buf := NewBuffer(nil)
+		err := Encode(buf, &tx)
+		AssertEqual(t, nil, err)
+
+		// Pointer fields keep one byte flag before value.
+		want := []byte{1}
+		want = binary.NativeEndian.AppendUint64(want, 1)
+		want = binary.NativeEndian.AppendUint64(want, 1)
+		want = append(want, 0)
+		want = binary.NativeEndian.AppendUint64(want, 0)
+		want = append(want, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+
+		AssertEqual(t, want, buf.Data())
+	})
+
+	t.Run("nil_flag", func(t *testing.T) {
+		in := []*uint64{nil, &one}
+		out := []*uint64{&two, nil}
+
+		buf := NewBuffer(nil)
+		err := Encode(buf, in)
+		AssertEqual(t, nil, err)
+		err = Decode(buf, &out)
+		AssertEqual(t, nil, err)
+
+		AssertEqual(t, in, out)
+	})
+}
+
+func TestTruncated(t *testing.T) {
+	in := Tx{Nonce: 7, Gas: 21000, Data: []byte{1, 2, 3}, AccessList: NamedTypeSlice1{4, 5, 6}}
+
+	buf := NewBuffer(nil)
+	err := Encode(buf, &in)
+	AssertEqual(t, nil, err)
+
+	data := buf.Data()
+
+	// Cut in the middle of AccessList elements.
+	out := Tx{}
+	err = Decode(NewBuffer(data[:len(data)-1]), &out)
+
+	Assert(t, true, errors.Is(err, ErrOutOfBounds))
+	Assert(t, true, strings.Contains(err.Error(), "Tx.AccessList"))
+	Assert(t, true, strings.Contains(err.Error(), "type=bitbox.NamedTypeSlice1"))
+
+	for i := 0; i < len(data); i++ {
+		out := Tx{}
+
+		err := Decode(NewBuffer(data[:i]), &out)
+		Assert(t, true, errors.Is(err, ErrOutOfBounds))
+	}
+}
+
+// Return number of bytes allocated by f.
+func allocatedBytes(f func()) uint64 {
+	var before, after runtime.MemStats
+
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+func TestLimits(t *testing.T) {
+	// Length prefix claims 4G elements, followed by single byte.
+	hostile := []byte{0xff, 0xff, 0xff, 0xff, 0x01}
+
+	t.Run("hostile_length_prefix", func(t *testing.T) {
+		outs := []any{
+			&[]byte{}, &[]uint64{}, &[]string{}, &[][]byte{}, &[]Tx{}, new(string),
+			&map[string]string{}, &map[int32][]uint16{}, &NamedTypeByte1{}, &NamedTypeSlice1{},
+		}
+
+		for _, out := range outs {
+			decode := func() {
+				err := Decode(NewBuffer(hostile), out)
+				Assert(t, true, errors.Is(err, ErrOutOfBounds))
+			}
+
+			// Warm up reflection caches, then only error formatting allocates.
+			decode()
+			Assert(t, true, allocatedBytes(decode) < 1024)
+		}
+	})
+
+	// Elements encoded as zero bytes still take memory.
+	t.Run("empty_encoded_elements", func(t *testing.T) {
+		type skipped struct {
+			Cache [64]byte `bitbox:"-"`
+		}
+
+		type unexported struct {
+			a uint64
+		}
+
+		tests := []struct {
+			name string
+			in   any
+			out  func() any
+		}{
+			{name: "skipped_fields", in: []skipped{{}}, out: func() any { return &[]skipped{} }},
+			{name: "skipped_map", in: map[skipped]skipped{{}: {}}, out: func() any { return &map[skipped]skipped{} }},
+			{name: "unexported_fields", in: []unexported{{a: 1}}, out: func() any { return &[]unexported{} }},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				for _, data := range [][]byte{{0, 0, 0, 1}, {0xff, 0xff, 0xff, 0xff}} {
+					out := tt.out()
+					decode := func() {
+						err := Decode(NewBuffer(data), out)
+						Assert(t, true, errors.Is(err, ErrOutOfBounds))
+					}
+
+					decode()
+					Assert(t, true, allocatedBytes(decode) < 1024)
+
+					buf := NewBuffer(data)
+					buf.SetLimits(Limits{MaxBytes: 1 << 20})
+					err := buf.Decode(tt.out())
+					Assert(t, true, err != nil)
+				}
+
+				// Encoder doesn't write data its decoder would reject.
+				err := Encode(NewBuffer(nil), tt.in)
+				Assert(t, true, errors.Is(err, ErrInvalidValue))
+			})
+		}
+
+		// Encoded unexported fields are counted as usual.
+		in := []unexported{{a: 1}, {a: 2}}
+		out := []unexported{}
+
+		buf := NewBuffer(nil)
+		buf.SetUnexported(true)
+		buf.SetLimits(Limits{MaxBytes: 1 << 20})
+
+		err := buf.Encode(in)
+		AssertEqual(t, nil, err)
+		err = buf.Decode(&out)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, in, out)
+	})
+
+	t.Run("max_elements", func(t *testing.T) {
+		buf := NewBuffer(nil)
+		err := Encode(buf, []uint16{1, 2, 3, 4}, "abcd", map[string]string{"a": "b"})
+		AssertEqual(t, nil, err)
+		data := buf.Data()
+
+		s := []uint16{}
+		str := ""
+		m := map[string]string{}
+
+		buf = NewBuffer(data)
+		buf.SetLimits(Limits{MaxElements: 3})
+		err = buf.Decode(&s)
+		Assert(t, true, errors.Is(err, ErrLimitExceeded))
+
+		buf = NewBuffer(data)
+		buf.SetLimits(Limits{MaxElements: 4})
+		err = buf.Decode(&s, &str, &m)
+		AssertEqual(t, nil, err)
+	})
+
+	t.Run("max_bytes", func(t *testing.T) {
+		in := [][]byte{make([]byte, 100), make([]byte, 100)}
+
+		buf := NewBuffer(nil)
+		err := Encode(buf, in)
+		AssertEqual(t, nil, err)
+		data := buf.Data()
+
+		out := [][]byte{}
+
+		buf = NewBuffer(data)
+		buf.SetLimits(Limits{MaxBytes: 150})
+		err = buf.Decode(&out)
+		Assert(t, true, errors.Is(err, ErrLimitExceeded))
+
+		buf = NewBuffer(data)
+		buf.SetLimits(Limits{MaxBytes: len(data)})
+		err = buf.Decode(&out)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, in, out)
+	})
+
+	t.Run("max_bytes_allocated", func(t *testing.T) {
+		// 100 empty strings take 404 bytes of input, but more memory.
+		in := make([]string, 100)
+		size := len(in) * int(unsafe.Sizeof(""))
+
+		buf := NewBuffer(nil)
+		err := Encode(buf, in)
+		AssertEqual(t, nil, err)
+		data := buf.Data()
+
+		out := []string{}
+
+		buf = NewBuffer(data)
+		buf.SetLimits(Limits{MaxBytes: 500})
+		err = buf.Decode(&out)
+		Assert(t, true, errors.Is(err, ErrLimitExceeded))
+		Assert(t, true, strings.Contains(err.Error(), "allocated bytes requested="+strconv.Itoa(size)))
+
+		buf = NewBuffer(data)
+		buf.SetLimits(Limits{MaxBytes: size})
+		err = buf.Decode(&out)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, in, out)
+	})
+
+	t.Run("max_depth", func(t *testing.T) {
+		type node struct {
+			Value uint64
+			Next  *node
+		}
+
+		in := &node{Value: 1, Next: &node{Value: 2, Next: &node{Value: 3}}}
+
+		buf := NewBuffer(nil)
+		err := Encode(buf, in, [][][]uint8{{{1}}})
+		AssertEqual(t, nil, err)
+		data := buf.Data()
+
+		out := node{}
+		outSlice := [][][]uint8{}
+
+		buf = NewBuffer(data)
+		buf.SetLimits(Limits{MaxDepth: 2})
+		err = buf.Decode(&out)
+		Assert(t, true, errors.Is(err, ErrLimitExceeded))
+
+		buf = NewBuffer(data)
+		buf.SetLimits(Limits{MaxDepth: 3})
+		err = buf.Decode(&out, &outSlice)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, *in, out)
+	})
+}
+
+func TestInvalidInput(t *testing.T) {
+	t.Run("nil_pointers", func(t *testing.T) {
+		buf := NewBuffer(nil)
+
+		err := Encode(buf, (*uint64)(nil), (*[]byte)(nil), (*Tx)(nil))
+		AssertEqual(t, nil, err)
+		Assert(t, 0, buf.Len())
+
+		// Nil maps are not pointers, they are written with zero count.
+		err = Encode(buf, map[string]string(nil), map[uint64]uint64(nil))
+		AssertEqual(t, nil, err)
+		Assert(t, 8, buf.Len())
+
+		buf = NewBuffer([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+		for _, out := range []any{nil, (*uint64)(nil), (*[]byte)(nil), (*Tx)(nil), uint64(0)} {
+			Assert(t, true, errors.Is(Decode(buf, out), ErrInvalidValue))
+			Assert(t, true, errors.Is(DecodePOD(buf, out), ErrInvalidValue))
+		}
+	})
+
+	t.Run("zero_sized_elements", func(t *testing.T) {
+		hostile := []byte{0xff, 0xff, 0xff, 0x7f}
+
+		out := []struct{}{}
+		err := Decode(NewBuffer(hostile), &out)
+		AssertEqual(t, nil, err)
+		Assert(t, 0x7fffffff, len(out))
+
+		m := map[struct{}]struct{}{}
+		err = Decode(NewBuffer(hostile), &m)
+		Assert(t, true, errors.Is(err, ErrInvalidValue))
+
+		in := map[struct{}]struct{}{{}: {}}
+		buf := NewBuffer(nil)
+		err = Encode(buf, in)
+		AssertEqual(t, nil, err)
+		err = Decode(buf, &m)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, in, m)
+	})
+}
+
 func TestNamedTypes(t *testing.T) {
 	// int
+	runEncoderDecoder(t, "named_int", NamedTypeInt1(-1))
 	runEncoderDecoder(t, "named_int8", NamedTypeInt2(-8))
 	runEncoderDecoder(t, "named_int16", NamedTypeInt3(-16))
 	runEncoderDecoder(t, "named_int32", NamedTypeInt4(-32))
 	runEncoderDecoder(t, "named_int64", NamedTypeInt5(-64))
 
 	// uint
+	runEncoderDecoder(t, "named_uint", NamedTypeUint1(1))
 	runEncoderDecoder(t, "named_uint8", NamedTypeUint2(8))
 	runEncoderDecoder(t, "named_uint16", NamedTypeUint3(16))
 	runEncoderDecoder(t, "named_uint32", NamedTypeUint4(32))
@@ -248,3 +820,113 @@ func TestNamedTypes(t *testing.T) {
 	runEncoderDecoder(t, "named_bool", NamedTypeBool1(true))
 	runEncoderDecoder(t, "named_bytes", NamedTypeByte1{10, 20, 30, 40})
 }
+
+func TestZeroCopy(t *testing.T) {
+	type message struct {
+		ID      uint32
+		Name    string
+		Payload []byte
+		Raw     NamedTypeByte1
+		Tags    []string
+		Chunks  [][]byte
+	}
+
+	in := message{
+		ID:      7,
+		Name:    "transfer",
+		Payload: []byte{1, 2, 3, 4},
+		Raw:     NamedTypeByte1{5, 6},
+		Tags:    []string{"a", "bc"},
+		Chunks:  [][]byte{{7}, {8, 9}},
+	}
+
+	enc := NewBuffer(nil)
+	err := Encode(enc, &in, "top", []byte{10, 11})
+	AssertEqual(t, nil, err)
+
+	data := enc.Data()
+
+	// Check if memory of decoded value is inside buffer data.
+	inData := func(p unsafe.Pointer) bool {
+		start := uintptr(unsafe.Pointer(unsafe.SliceData(data)))
+		return uintptr(p) >= start && uintptr(p) < start+uintptr(len(data))
+	}
+
+	t.Run("aliased", func(t *testing.T) {
+		out := message{}
+		top := ""
+		raw := []byte{}
+
+		buf := NewBuffer(data)
+		buf.SetZeroCopy(true)
+
+		err := buf.Decode(&out, &top, &raw)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, in, out)
+		Assert(t, "top", top)
+
+		Assert(t, true, inData(unsafe.Pointer(unsafe.StringData(out.Name))))
+		Assert(t, true, inData(unsafe.Pointer(&out.Payload[0])))
+		Assert(t, true, inData(unsafe.Pointer(&out.Raw[0])))
+		Assert(t, true, inData(unsafe.Pointer(unsafe.StringData(out.Tags[1]))))
+		Assert(t, true, inData(unsafe.Pointer(&out.Chunks[1][0])))
+		Assert(t, true, inData(unsafe.Pointer(unsafe.StringData(top))))
+		Assert(t, true, inData(unsafe.Pointer(&raw[0])))
+
+		// Appending can't overwrite buffer data.
+		Assert(t, len(out.Payload), cap(out.Payload))
+		Assert(t, len(out.Raw), cap(out.Raw))
+	})
+
+	t.Run("allocs", func(t *testing.T) {
+		out := message{}
+
+		decode := func(zeroCopy bool) float64 {
+			buf := NewBuffer(nil)
+			buf.SetZeroCopy(zeroCopy)
+
+			return testing.AllocsPerRun(100, func() {
+				buf.data, buf.off = data, 0
+				buf.Decode(&out)
+			})
+		}
+
+		// Slices of strings and byte slices are reused, only data is copied.
+		Assert(t, 0, decode(true))
+		Assert(t, true, decode(false) > 0)
+	})
+
+	t.Run("copied", func(t *testing.T) {
+		out := message{}
+
+		err := Decode(NewBuffer(data), &out)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, in, out)
+		Assert(t, false, inData(unsafe.Pointer(unsafe.StringData(out.Name))))
+		Assert(t, false, inData(unsafe.Pointer(&out.Payload[0])))
+
+		// Streaming buffers reuse their memory, so they always copy.
+		dec := NewDecoder(bytes.NewReader(data))
+		dec.buf.SetZeroCopy(true)
+
+		out = message{}
+		err = dec.Decode(&out)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, in, out)
+		Assert(t, false, dec.buf.aliasing())
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		empty := message{}
+		out := message{}
+
+		buf := NewBuffer(nil)
+		err := Encode(buf, &empty)
+		AssertEqual(t, nil, err)
+
+		buf.SetZeroCopy(true)
+		err = buf.Decode(&out)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, empty, out)
+	})
+}
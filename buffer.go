@@ -1,9 +1,46 @@
 package bitbox
 
+import (
+	"io"
+	"unsafe"
+)
+
 // Buffer class for encoding/decoding data.
 type Buffer struct {
 	data []byte
 	off  int
+	opts options
+
+	// Streaming - when w is set, data is flushed into it
+	// every time buffer grows over chunk size. When r is set,
+	// buffer is refilled from it on demand.
+	w     io.Writer
+	r     io.Reader
+	chunk int
+	eof   bool
+	err   error
+
+	// Decoding limits - number of bytes read so far,
+	// position where top level Decode started and nesting depth.
+	pos   int
+	start int
+	depth int
+
+	// Number of bytes written so far, data can be flushed in between.
+	written int
+
+	// Plan of last encoded/decoded top level value.
+	last *plan
+}
+
+// Encoding/decoding settings, shared with temporary buffers.
+type options struct {
+	deterministic bool
+	swap          bool
+	text          bool
+	unexported    bool
+	zeroCopy      bool
+	limits        Limits
 }
 
 // Create new Buffer.
@@ -11,6 +48,72 @@ func NewBuffer(data []byte) *Buffer {
 	return &Buffer{data: data, off: 0}
 }
 
+// Sort map entries by their encoded keys, so the same value
+// always produces the same bytes. Off by default, since it's slower.
+// Maps with different keys encoded the same way return ErrInvalidValue.
+func (b *Buffer) SetDeterministic(on bool) {
+	b.opts.deterministic = on
+}
+
+// Set byte order used for fixed types, length prefixes and bulk copies.
+// When order matches host, data is copied directly without swapping.
+func (b *Buffer) SetByteOrder(order ByteOrder) {
+	b.opts.swap = needSwap(order)
+}
+
+// Use encoding.TextMarshaler/TextUnmarshaler for types
+// without binary or bitbox encoding. Off by default.
+func (b *Buffer) SetTextMarshaler(on bool) {
+	b.opts.text = on
+}
+
+// Encode unexported struct fields, using unsafe access.
+// Off by default, unexported fields are skipped.
+// Both encoder and decoder must use the same setting.
+func (b *Buffer) SetUnexported(on bool) {
+	b.opts.unexported = on
+}
+
+// Report if unexported struct fields are encoded.
+func (b *Buffer) Unexported() bool {
+	return b.opts.unexported
+}
+
+// Decode strings and byte slices without copying, they point directly into
+// buffer data. Off by default.
+//
+// Decoded values are valid only as long as buffer data is not modified:
+// don't Clear and reuse the buffer or change slice passed to NewBuffer while
+// they are in use, strings would change their content. Byte slices have
+// capacity limited to their length, so append never writes into buffer.
+// Streaming buffers (Decoder) reuse their memory, so they always copy.
+func (b *Buffer) SetZeroCopy(on bool) {
+	b.opts.zeroCopy = on
+}
+
+// Report if decoded values can point into buffer data.
+func (b *Buffer) aliasing() bool {
+	return b.opts.zeroCopy && b.r == nil
+}
+
+// Make string from bytes taken from buffer.
+func (b *Buffer) toString(data []byte) string {
+	if b.aliasing() && len(data) > 0 {
+		return unsafe.String(&data[0], len(data))
+	}
+	return string(data)
+}
+
+// Set limits for decoding untrusted data.
+func (b *Buffer) SetLimits(limits Limits) {
+	b.opts.limits = limits
+}
+
+// Create empty buffer with the same settings.
+func (b *Buffer) scratch() *Buffer {
+	return &Buffer{opts: b.opts}
+}
+
 // Encode data from objects into buffer.
 func (b *Buffer) Encode(objects ...any) error {
 	return Encode(b, objects...)
@@ -21,11 +124,31 @@ func (b *Buffer) EncodePOD(object any) error {
 	return EncodePOD(b, object)
 }
 
+// Encode POD objects without checking their type. See EncodePODUnchecked.
+func (b *Buffer) EncodePODUnchecked(object any) error {
+	return EncodePODUnchecked(b, object)
+}
+
 // Decode POD objects.
 func (b *Buffer) DecodePOD(object any) error {
 	return DecodePOD(b, object)
 }
 
+// Decode POD objects without checking their type. See DecodePODUnchecked.
+func (b *Buffer) DecodePODUnchecked(object any) error {
+	return DecodePODUnchecked(b, object)
+}
+
+// Encode object in packed layout. See EncodePacked.
+func (b *Buffer) EncodePacked(object any) error {
+	return EncodePacked(b, object)
+}
+
+// Decode object in packed layout. See DecodePacked.
+func (b *Buffer) DecodePacked(object any) error {
+	return DecodePacked(b, object)
+}
+
 // Decode data from buffer into objects.
 func (b *Buffer) Decode(objects ...any) error {
 	return Decode(b, objects...)
@@ -38,20 +161,119 @@ func (b *Buffer) Len() int {
 
 // Read data from buffer into dst.
 func (b *Buffer) Read(dst []byte) int {
+	if b.r != nil && len(b.data)-b.off < len(dst) {
+		b.fill(len(dst))
+	}
+
 	n := copy(dst, b.data[b.off:])
 	b.off += n
+	b.pos += n
 
+	// Short read is kept as io.ErrUnexpectedEOF.
+	if n < len(dst) && b.r != nil && b.err == nil {
+		b.err = io.ErrUnexpectedEOF
+	}
 	return n
 }
 
+// Read big values directly from underlying reader, skipping internal chunk.
+// Read doesn't pass dst to reader, so small values read with it stay on stack.
+func (b *Buffer) readDirect(dst []byte) int {
+	n := copy(dst, b.data[b.off:])
+	b.off += n
+
+	if n < len(dst) && b.err == nil && !b.eof {
+		m, err := io.ReadFull(b.r, dst[n:])
+		n += m
+
+		switch err {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF:
+			b.eof = true
+		default:
+			b.err = err
+		}
+	}
+
+	b.pos += n
+
+	if n < len(dst) && b.err == nil {
+		b.err = io.ErrUnexpectedEOF
+	}
+	return n
+}
+
+// Make sure at least n bytes are buffered, reading from underlying reader.
+// Buffer grows with received data, not with requested size.
+func (b *Buffer) fill(n int) error {
+	for len(b.data)-b.off < n {
+		if b.err != nil {
+			return b.err
+		}
+
+		if b.eof {
+			return io.ErrUnexpectedEOF
+		}
+
+		// Move remaining bytes to the front.
+		if b.off > 0 {
+			l := copy(b.data, b.data[b.off:])
+			b.data = b.data[:l]
+			b.off = 0
+		}
+
+		if len(b.data) == cap(b.data) {
+			data := make([]byte, len(b.data), 2*cap(b.data)+b.chunk)
+			copy(data, b.data)
+			b.data = data
+		}
+
+		m, err := b.r.Read(b.data[len(b.data):cap(b.data)])
+		b.data = b.data[:len(b.data)+m]
+
+		if err == io.EOF {
+			b.eof = true
+		} else if err != nil {
+			b.err = err
+		}
+	}
+	return nil
+}
+
 // Write data from src into buffer.
 func (b *Buffer) Write(src []byte) {
+	// Flush before appending, so written bytes stay
+	// in buffer until next write (they can be swapped in place).
+	if b.w != nil && len(b.data) > 0 && len(b.data)+len(src) > b.chunk {
+		b.flush()
+	}
+
 	b.data = append(b.data, src...)
+	b.written += len(src)
+}
+
+// Write buffered data into underlying writer.
+// After first error all data is dropped and error is kept.
+func (b *Buffer) flush() error {
+	if b.err == nil && len(b.data) > 0 {
+		_, b.err = b.w.Write(b.data)
+	}
+
+	b.data = b.data[:0]
+	return b.err
 }
 
 // Take next N bytes from buffer.
-// This will advance offset.
+// This will advance offset. When buffer is streaming, returned
+// bytes are valid only until next read.
 func (b *Buffer) Next(num int) ([]byte, error) {
+	if b.r != nil {
+		err := b.fill(num)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	limit := b.off + num
 
 	if limit > len(b.data) {
@@ -60,6 +282,7 @@ func (b *Buffer) Next(num int) ([]byte, error) {
 
 	old := b.off
 	b.off += num
+	b.pos += num
 
 	return b.data[old:b.off], nil
 }
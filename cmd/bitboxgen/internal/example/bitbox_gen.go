@@ -0,0 +1,384 @@
+// Code generated by bitboxgen. DO NOT EDIT.
+
+package example
+
+import (
+	"fmt"
+
+	"github.com/datagentleman/bitbox"
+)
+
+// EncodeBitbox writes Tx in the same format as bitbox.Encode.
+func (v *Tx) EncodeBitbox(buf *bitbox.Buffer) error {
+	// ChainID
+	bitbox.WriteFlag(buf, v.ChainID != nil)
+	if v.ChainID != nil {
+		bitbox.WriteFixed(buf, v.ChainID)
+	}
+
+	// Nonce
+	bitbox.WriteFixed(buf, &v.Nonce)
+
+	// GasPrice
+	bitbox.WriteFlag(buf, v.GasPrice != nil)
+	if v.GasPrice != nil {
+		bitbox.WriteFixed(buf, v.GasPrice)
+	}
+
+	// Gas
+	bitbox.WriteFixed(buf, &v.Gas)
+
+	// To
+	bitbox.WriteFlag(buf, v.To != nil)
+	if v.To != nil {
+		bitbox.WriteFixed(buf, v.To)
+	}
+
+	// Value
+	bitbox.WriteFlag(buf, v.Value != nil)
+	if v.Value != nil {
+		bitbox.WriteFixed(buf, v.Value)
+	}
+
+	// Data
+	bitbox.WriteSlice(buf, v.Data)
+
+	// AccessList
+	bitbox.WriteSlice(buf, []uint16(v.AccessList))
+	return nil
+}
+
+// DecodeBitbox reads Tx written by EncodeBitbox or bitbox.Encode.
+func (v *Tx) DecodeBitbox(buf *bitbox.Buffer) error {
+	// ChainID
+	if set, err := bitbox.ReadFlag(buf); err != nil {
+		return fmt.Errorf("example.Tx.ChainID: %w", err)
+	} else if !set {
+		v.ChainID = nil
+	} else {
+		if v.ChainID == nil {
+			v.ChainID = new(uint64)
+		}
+		if err := bitbox.ReadFixed(buf, v.ChainID); err != nil {
+			return fmt.Errorf("example.Tx.ChainID: %w", err)
+		}
+	}
+
+	// Nonce
+	if err := bitbox.ReadFixed(buf, &v.Nonce); err != nil {
+		return fmt.Errorf("example.Tx.Nonce: %w", err)
+	}
+
+	// GasPrice
+	if set, err := bitbox.ReadFlag(buf); err != nil {
+		return fmt.Errorf("example.Tx.GasPrice: %w", err)
+	} else if !set {
+		v.GasPrice = nil
+	} else {
+		if v.GasPrice == nil {
+			v.GasPrice = new(uint64)
+		}
+		if err := bitbox.ReadFixed(buf, v.GasPrice); err != nil {
+			return fmt.Errorf("example.Tx.GasPrice: %w", err)
+		}
+	}
+
+	// Gas
+	if err := bitbox.ReadFixed(buf, &v.Gas); err != nil {
+		return fmt.Errorf("example.Tx.Gas: %w", err)
+	}
+
+	// To
+	if set, err := bitbox.ReadFlag(buf); err != nil {
+		return fmt.Errorf("example.Tx.To: %w", err)
+	} else if !set {
+		v.To = nil
+	} else {
+		if v.To == nil {
+			v.To = new(Address)
+		}
+		if err := bitbox.ReadFixed(buf, v.To); err != nil {
+			return fmt.Errorf("example.Tx.To: %w", err)
+		}
+	}
+
+	// Value
+	if set, err := bitbox.ReadFlag(buf); err != nil {
+		return fmt.Errorf("example.Tx.Value: %w", err)
+	} else if !set {
+		v.Value = nil
+	} else {
+		if v.Value == nil {
+			v.Value = new(uint64)
+		}
+		if err := bitbox.ReadFixed(buf, v.Value); err != nil {
+			return fmt.Errorf("example.Tx.Value: %w", err)
+		}
+	}
+
+	// Data
+	if err := bitbox.ReadSlice(buf, &v.Data); err != nil {
+		return fmt.Errorf("example.Tx.Data: %w", err)
+	}
+
+	// AccessList
+	if err := bitbox.ReadSlice(buf, (*[]uint16)(&v.AccessList)); err != nil {
+		return fmt.Errorf("example.Tx.AccessList: %w", err)
+	}
+	return nil
+}
+
+// EncodeBitbox writes Header in the same format as bitbox.Encode.
+func (v *Header) EncodeBitbox(buf *bitbox.Buffer) error {
+	// Number
+	bitbox.WriteInt(buf, v.Number)
+
+	// Time
+	if err := bitbox.EncodeNested(buf, &v.Time); err != nil {
+		return err
+	}
+
+	// Coinbase
+	bitbox.WriteFixed(buf, &v.Coinbase)
+
+	// Extra
+	if v.Extra == "" {
+		bitbox.WriteFlag(buf, false)
+	} else {
+		bitbox.WriteFlag(buf, true)
+		bitbox.WriteString(buf, v.Extra)
+	}
+	return nil
+}
+
+// DecodeBitbox reads Header written by EncodeBitbox or bitbox.Encode.
+func (v *Header) DecodeBitbox(buf *bitbox.Buffer) error {
+	// Number
+	if err := bitbox.ReadInt(buf, &v.Number); err != nil {
+		return fmt.Errorf("example.Header.Number: %w", err)
+	}
+
+	// Time
+	if err := bitbox.DecodeNested(buf, &v.Time); err != nil {
+		return fmt.Errorf("example.Header.Time: %w", err)
+	}
+
+	// Coinbase
+	if err := bitbox.ReadFixed(buf, &v.Coinbase); err != nil {
+		return fmt.Errorf("example.Header.Coinbase: %w", err)
+	}
+
+	// Extra
+	if set, err := bitbox.ReadFlag(buf); err != nil {
+		return fmt.Errorf("example.Header.Extra: %w", err)
+	} else if !set {
+		v.Extra = ""
+	} else {
+		if err := bitbox.ReadString(buf, &v.Extra); err != nil {
+			return fmt.Errorf("example.Header.Extra: %w", err)
+		}
+	}
+	return nil
+}
+
+// EncodeBitbox writes Block in the same format as bitbox.Encode.
+func (v *Block) EncodeBitbox(buf *bitbox.Buffer) error {
+	// Hash
+	bitbox.WriteFixed(buf, &v.Hash)
+
+	// Header
+	if err := v.Header.EncodeBitbox(buf); err != nil {
+		return err
+	}
+
+	// Txs
+	bitbox.WriteLen(buf, len(v.Txs))
+	for i0 := range v.Txs {
+		if err := v.Txs[i0].EncodeBitbox(buf); err != nil {
+			return err
+		}
+	}
+
+	// Parent
+	bitbox.WriteFlag(buf, v.Parent != nil)
+	if v.Parent != nil {
+		if err := v.Parent.EncodeBitbox(buf); err != nil {
+			return err
+		}
+	}
+
+	// Uncles
+	bitbox.WriteLen(buf, len(v.Uncles))
+	for i0 := range v.Uncles {
+		bitbox.WriteFlag(buf, v.Uncles[i0] != nil)
+		if v.Uncles[i0] != nil {
+			if err := v.Uncles[i0].EncodeBitbox(buf); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Labels
+	if err := bitbox.EncodeNested(buf, &v.Labels); err != nil {
+		return err
+	}
+
+	// Tags
+	bitbox.WriteLen(buf, len(v.Tags))
+	for i0 := range v.Tags {
+		bitbox.WriteString(buf, v.Tags[i0])
+	}
+
+	// Grid
+	bitbox.WriteFixed(buf, &v.Grid)
+
+	// Sizes
+	bitbox.WriteLen(buf, len(v.Sizes))
+	for i0 := range v.Sizes {
+		bitbox.WriteInt(buf, v.Sizes[i0])
+	}
+
+	// Counts
+	for i0 := range v.Counts {
+		bitbox.WriteUint(buf, v.Counts[i0])
+	}
+
+	// Ready
+	bitbox.WriteFixed(buf, &v.Ready)
+
+	// note
+	if buf.Unexported() {
+		bitbox.WriteString(buf, v.note)
+	}
+	return nil
+}
+
+// DecodeBitbox reads Block written by EncodeBitbox or bitbox.Encode.
+func (v *Block) DecodeBitbox(buf *bitbox.Buffer) error {
+	// Hash
+	if err := bitbox.ReadFixed(buf, &v.Hash); err != nil {
+		return fmt.Errorf("example.Block.Hash: %w", err)
+	}
+
+	// Header
+	if err := bitbox.DecodeUnmarshaler(buf, &v.Header); err != nil {
+		return fmt.Errorf("example.Block.Header: %w", err)
+	}
+
+	// Txs
+	if n, err := bitbox.ReadLen[[]Tx](buf); err != nil {
+		return fmt.Errorf("example.Block.Txs: %w", err)
+	} else {
+		if cap(v.Txs) < n {
+			v.Txs = make([]Tx, n)
+		} else {
+			v.Txs = v.Txs[:n]
+		}
+		for i0 := range v.Txs {
+			if err := bitbox.DecodeUnmarshaler(buf, &v.Txs[i0]); err != nil {
+				return fmt.Errorf("example.Block.Txs: %w", err)
+			}
+		}
+	}
+
+	// Parent
+	if set, err := bitbox.ReadFlag(buf); err != nil {
+		return fmt.Errorf("example.Block.Parent: %w", err)
+	} else if !set {
+		v.Parent = nil
+	} else {
+		if v.Parent == nil {
+			v.Parent = new(Block)
+		}
+		if err := bitbox.DecodeUnmarshaler(buf, v.Parent); err != nil {
+			return fmt.Errorf("example.Block.Parent: %w", err)
+		}
+	}
+
+	// Uncles
+	if n, err := bitbox.ReadLen[[]*Header](buf); err != nil {
+		return fmt.Errorf("example.Block.Uncles: %w", err)
+	} else {
+		if cap(v.Uncles) < n {
+			v.Uncles = make([]*Header, n)
+		} else {
+			v.Uncles = v.Uncles[:n]
+		}
+		for i0 := range v.Uncles {
+			if set, err := bitbox.ReadFlag(buf); err != nil {
+				return fmt.Errorf("example.Block.Uncles: %w", err)
+			} else if !set {
+				v.Uncles[i0] = nil
+			} else {
+				if v.Uncles[i0] == nil {
+					v.Uncles[i0] = new(Header)
+				}
+				if err := bitbox.DecodeUnmarshaler(buf, v.Uncles[i0]); err != nil {
+					return fmt.Errorf("example.Block.Uncles: %w", err)
+				}
+			}
+		}
+	}
+
+	// Labels
+	if err := bitbox.DecodeNested(buf, &v.Labels); err != nil {
+		return fmt.Errorf("example.Block.Labels: %w", err)
+	}
+
+	// Tags
+	if n, err := bitbox.ReadLen[[]string](buf); err != nil {
+		return fmt.Errorf("example.Block.Tags: %w", err)
+	} else {
+		if cap(v.Tags) < n {
+			v.Tags = make([]string, n)
+		} else {
+			v.Tags = v.Tags[:n]
+		}
+		for i0 := range v.Tags {
+			if err := bitbox.ReadString(buf, &v.Tags[i0]); err != nil {
+				return fmt.Errorf("example.Block.Tags: %w", err)
+			}
+		}
+	}
+
+	// Grid
+	if err := bitbox.ReadFixed(buf, &v.Grid); err != nil {
+		return fmt.Errorf("example.Block.Grid: %w", err)
+	}
+
+	// Sizes
+	if n, err := bitbox.ReadLen[[]int](buf); err != nil {
+		return fmt.Errorf("example.Block.Sizes: %w", err)
+	} else {
+		if cap(v.Sizes) < n {
+			v.Sizes = make([]int, n)
+		} else {
+			v.Sizes = v.Sizes[:n]
+		}
+		for i0 := range v.Sizes {
+			if err := bitbox.ReadInt(buf, &v.Sizes[i0]); err != nil {
+				return fmt.Errorf("example.Block.Sizes: %w", err)
+			}
+		}
+	}
+
+	// Counts
+	for i0 := range v.Counts {
+		if err := bitbox.ReadUint(buf, &v.Counts[i0]); err != nil {
+			return fmt.Errorf("example.Block.Counts: %w", err)
+		}
+	}
+
+	// Ready
+	if err := bitbox.ReadFixed(buf, &v.Ready); err != nil {
+		return fmt.Errorf("example.Block.Ready: %w", err)
+	}
+
+	// note
+	if buf.Unexported() {
+		if err := bitbox.ReadString(buf, &v.note); err != nil {
+			return fmt.Errorf("example.Block.note: %w", err)
+		}
+	}
+	return nil
+}
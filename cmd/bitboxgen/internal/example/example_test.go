@@ -0,0 +1,157 @@
+package example
+
+import (
+	"bytes"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/datagentleman/bitbox"
+)
+
+// Types without generated methods, encoded with reflection.
+// Nested generated types are checked by their own plain types.
+type plainTx Tx
+type plainHeader Header
+type plainBlock Block
+
+func makeBlock() Block {
+	chainID := uint64(11155111)
+	value := uint64(12345)
+	to := Address{}
+
+	for i := range to {
+		to[i] = uint8(i + 1)
+	}
+
+	header := Header{
+		Number:   -7,
+		Time:     time.Date(2024, 5, 17, 10, 30, 15, 123456789, time.UTC),
+		Coinbase: to,
+		Extra:    "extra",
+	}
+
+	return Block{
+		Hash:   [32]byte{1, 2, 3},
+		Header: header,
+		Txs: []Tx{
+			{ChainID: &chainID, Nonce: 42, Gas: 21000, To: &to, Value: &value, Data: []byte{9, 8, 7}, AccessList: AccessList{1, 3, 5}},
+			{Nonce: 1},
+		},
+		Parent: &Block{Header: Header{Number: 1, Time: header.Time}, Counts: [3]uint{1, 2, 3}},
+		Uncles: []*Header{&header, nil, {Number: 2, Time: header.Time}},
+		Labels: map[string]uint32{"a": 1},
+		Tags:   []string{"x", "", "yz"},
+		Grid:   [2][3]uint16{{1, 2, 3}, {4, 5, 6}},
+		Sizes:  []int{-1, 0, 1 << 30},
+		Counts: [3]uint{7, 8, 9},
+		Ready:  true,
+		note:   "note",
+	}
+}
+
+// Check that generated code writes the same bytes as reflection and that
+// both decode each other's output.
+func runGenerated[T any, P interface {
+	*T
+	bitbox.Marshaler
+	bitbox.Unmarshaler
+}, R any](t *testing.T, name string, in T, setup func(*bitbox.Buffer)) {
+	t.Helper()
+
+	t.Run(name, func(t *testing.T) {
+		plain := (*R)(unsafe.Pointer(&in))
+
+		generated := bitbox.NewBuffer(nil)
+		reflected := bitbox.NewBuffer(nil)
+		setup(generated)
+		setup(reflected)
+
+		err := P(&in).EncodeBitbox(generated)
+		bitbox.AssertEqual(t, nil, err)
+
+		err = bitbox.Encode(reflected, plain)
+		bitbox.AssertEqual(t, nil, err)
+
+		bitbox.Assert(t, true, bytes.Equal(generated.Data(), reflected.Data()))
+
+		out := new(T)
+		err = P(out).DecodeBitbox(reflected)
+		bitbox.AssertEqual(t, nil, err)
+		bitbox.AssertEqual(t, in, *out)
+
+		plainOut := new(R)
+		err = bitbox.Decode(generated, plainOut)
+		bitbox.AssertEqual(t, nil, err)
+		bitbox.AssertEqual(t, *plain, *plainOut)
+	})
+}
+
+func TestGenerated(t *testing.T) {
+	orders := []struct {
+		name  string
+		order bitbox.ByteOrder
+	}{
+		{name: "little", order: bitbox.LittleEndian},
+		{name: "big", order: bitbox.BigEndian},
+	}
+
+	block := makeBlock()
+	exported := block
+	exported.note = ""
+
+	for _, o := range orders {
+		t.Run(o.name, func(t *testing.T) {
+			setup := func(buf *bitbox.Buffer) {
+				buf.SetByteOrder(o.order)
+			}
+
+			unexported := func(buf *bitbox.Buffer) {
+				buf.SetByteOrder(o.order)
+				buf.SetUnexported(true)
+			}
+
+			runGenerated[Tx, *Tx, plainTx](t, "tx", block.Txs[0], setup)
+			runGenerated[Tx, *Tx, plainTx](t, "empty_tx", Tx{}, setup)
+			runGenerated[Header, *Header, plainHeader](t, "header", block.Header, setup)
+			runGenerated[Header, *Header, plainHeader](t, "omitted", Header{Time: block.Header.Time}, setup)
+			runGenerated[Block, *Block, plainBlock](t, "block", exported, setup)
+			runGenerated[Block, *Block, plainBlock](t, "unexported", block, unexported)
+		})
+	}
+}
+
+// Decoding reuses existing values, same as Decode.
+func TestGeneratedReuse(t *testing.T) {
+	in := makeBlock()
+	in.note = ""
+	out := makeBlock()
+	out.note = ""
+	out.Tags = append(out.Tags, "more")
+	out.Parent = nil
+
+	buf := bitbox.NewBuffer(nil)
+
+	err := bitbox.Encode(buf, &in)
+	bitbox.AssertEqual(t, nil, err)
+
+	err = bitbox.Decode(buf, &out)
+	bitbox.AssertEqual(t, nil, err)
+	bitbox.AssertEqual(t, in, out)
+}
+
+func TestGeneratedErrors(t *testing.T) {
+	in := makeBlock()
+	buf := bitbox.NewBuffer(nil)
+
+	err := bitbox.Encode(buf, &in)
+	bitbox.AssertEqual(t, nil, err)
+
+	data := buf.Data()
+
+	for _, n := range []int{0, 1, 40, len(data) / 2, len(data) - 1} {
+		out := Block{}
+		err := bitbox.Decode(bitbox.NewBuffer(bytes.Clone(data[:n])), &out)
+		bitbox.Assert(t, true, err != nil)
+	}
+}
@@ -0,0 +1,43 @@
+// Package example has types used to test code generated by bitboxgen.
+package example
+
+import "time"
+
+//go:generate go run github.com/datagentleman/bitbox/cmd/bitboxgen -type Tx,Header,Block
+
+type Address [32]uint8
+type AccessList []uint16
+
+type Tx struct {
+	ChainID    *uint64
+	Nonce      uint64
+	GasPrice   *uint64
+	Gas        uint64
+	To         *Address
+	Value      *uint64
+	Data       []byte
+	AccessList AccessList
+}
+
+type Header struct {
+	Number   int
+	Time     time.Time
+	Coinbase Address
+	Extra    string `bitbox:",omitempty"`
+}
+
+type Block struct {
+	Hash    [32]byte `bitbox:"0"`
+	Header  Header   `bitbox:"1"`
+	Txs     []Tx
+	Parent  *Block
+	Uncles  []*Header
+	Labels  map[string]uint32
+	Tags    []string
+	Grid    [2][3]uint16
+	Sizes   []int
+	Counts  [3]uint
+	Ready   bool
+	Skipped string `bitbox:"-"`
+	note    string
+}
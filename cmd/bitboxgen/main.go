@@ -0,0 +1,573 @@
+// Bitboxgen generates EncodeBitbox/DecodeBitbox methods for struct types.
+// Generated methods write exactly the same bytes as bitbox.Encode,
+// without using reflection.
+//
+// Usage:
+//
+//	//go:generate go run github.com/datagentleman/bitbox/cmd/bitboxgen -type Tx,Block
+//
+// Flags:
+//
+//	-type    comma separated list of struct types, required
+//	-output  output file, default bitbox_gen.go in package directory
+//
+// Numbers, strings, pointers, slices, arrays and other generated types are
+// encoded directly. Remaining fields (maps, interfaces, types from other
+// packages or with custom encoding, ...) use bitbox.EncodeNested and
+// bitbox.DecodeNested, which fall back to reflection.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma separated list of struct types")
+	output := flag.String("output", "", "output file, default bitbox_gen.go")
+	flag.Parse()
+
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	out := *output
+	if out == "" {
+		out = filepath.Join(dir, "bitbox_gen.go")
+	}
+
+	src, err := generate(dir, strings.Split(*typeNames, ","), filepath.Base(out))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bitboxgen:", err)
+		os.Exit(1)
+	}
+
+	err = os.WriteFile(out, src, 0o644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bitboxgen:", err)
+		os.Exit(1)
+	}
+}
+
+// How values of type are encoded.
+type codec int
+
+const (
+	codecNested codec = iota
+	codecGenerated
+	codecFixed
+	codecInt
+	codecUint
+	codecString
+	codecFixedSlice
+	codecSlice
+	codecArray
+	codecPointer
+)
+
+type generator struct {
+	pkg   *types.Package
+	types map[*types.TypeName]bool
+	buf   bytes.Buffer
+
+	// Name of current type and field, used in errors.
+	typeName  string
+	fieldName string
+
+	// Nesting of loops, used for index names.
+	depth int
+}
+
+// Generate source with methods for given types of package in dir.
+// Output file is skipped when parsing, so it can be regenerated.
+func generate(dir string, names []string, output string) ([]byte, error) {
+	pkg, err := loadPackage(dir, output)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{pkg: pkg, types: map[*types.TypeName]bool{}}
+	structs := []*types.Named{}
+
+	for _, name := range names {
+		obj, ok := pkg.Scope().Lookup(strings.TrimSpace(name)).(*types.TypeName)
+		if !ok {
+			return nil, fmt.Errorf("type %s not found", name)
+		}
+
+		named, ok := obj.Type().(*types.Named)
+		if !ok || named.TypeParams().Len() > 0 {
+			return nil, fmt.Errorf("%s is not a struct type", name)
+		}
+
+		if _, ok := named.Underlying().(*types.Struct); !ok {
+			return nil, fmt.Errorf("%s is not a struct type", name)
+		}
+
+		g.types[obj] = true
+		structs = append(structs, named)
+	}
+
+	g.printf("// Code generated by bitboxgen. DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", pkg.Name())
+	g.printf("import (\n\"fmt\"\n\n\"github.com/datagentleman/bitbox\"\n)\n")
+
+	for _, named := range structs {
+		err := g.generateType(named)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return src, nil
+}
+
+// Parse and type check package. Type errors are ignored,
+// types which can't be resolved are encoded with reflection.
+func loadPackage(dir string, output string) (*types.Package, error) {
+	fset := token.NewFileSet()
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	files := []*ast.File{}
+
+	for _, path := range paths {
+		name := filepath.Base(path)
+		if strings.HasSuffix(name, "_test.go") || name == output {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error:    func(error) {},
+	}
+
+	pkg, _ := conf.Check(files[0].Name.Name, fset, files, nil)
+	return pkg, nil
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// Encoded struct field, same as in bitbox.
+type field struct {
+	v         *types.Var
+	pos       int
+	omitEmpty bool
+}
+
+// Get encoded fields in the same order as bitbox does.
+func (g *generator) fields(named *types.Named) ([]field, error) {
+	st := named.Underlying().(*types.Struct)
+	fields := []field{}
+	positions := map[int]string{}
+
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		f := field{v: v, pos: -1}
+
+		tag, ok := reflect.StructTag(st.Tag(i)).Lookup("bitbox")
+		if tag == "-" {
+			continue
+		}
+
+		if v.Name() == "_" {
+			return nil, fmt.Errorf("%s: blank fields are not supported", named.Obj().Name())
+		}
+
+		if ok {
+			pos, opts, _ := strings.Cut(tag, ",")
+
+			for _, opt := range strings.Split(opts, ",") {
+				switch opt {
+				case "":
+				case "omitempty":
+					f.omitEmpty = true
+				default:
+					return nil, fmt.Errorf("%s.%s: unknown option %s", named.Obj().Name(), v.Name(), opt)
+				}
+			}
+
+			if pos != "" {
+				n, err := strconv.Atoi(pos)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("%s.%s: invalid position %s", named.Obj().Name(), v.Name(), pos)
+				}
+
+				if other, ok := positions[n]; ok {
+					return nil, fmt.Errorf("%s.%s: has the same position as %s", named.Obj().Name(), v.Name(), other)
+				}
+
+				positions[n] = v.Name()
+				f.pos = n
+			}
+		}
+
+		fields = append(fields, f)
+	}
+
+	sort.SliceStable(fields, func(a, b int) bool {
+		pa, pb := fields[a].pos, fields[b].pos
+
+		if pa >= 0 && pb >= 0 {
+			return pa < pb
+		}
+		return pa >= 0 && pb < 0
+	})
+	return fields, nil
+}
+
+func (g *generator) generateType(named *types.Named) error {
+	name := named.Obj().Name()
+	g.typeName = g.pkg.Name() + "." + name
+
+	fields, err := g.fields(named)
+	if err != nil {
+		return err
+	}
+
+	if len(fields) == 0 {
+		return fmt.Errorf("%s has no encoded fields", name)
+	}
+
+	// Encode
+	g.printf("\n// EncodeBitbox writes %s in the same format as bitbox.Encode.\n", name)
+	g.printf("func (v *%s) EncodeBitbox(buf *bitbox.Buffer) error {\n", name)
+
+	for i, f := range fields {
+		err := g.field(i, f, g.encode)
+		if err != nil {
+			return err
+		}
+	}
+
+	g.printf("return nil\n}\n")
+
+	// Decode
+	g.printf("\n// DecodeBitbox reads %s written by EncodeBitbox or bitbox.Encode.\n", name)
+	g.printf("func (v *%s) DecodeBitbox(buf *bitbox.Buffer) error {\n", name)
+
+	for i, f := range fields {
+		err := g.field(i, f, g.decode)
+		if err != nil {
+			return err
+		}
+	}
+
+	g.printf("return nil\n}\n")
+	return nil
+}
+
+// Generate code of one field, handling unexported and omitted fields.
+func (g *generator) field(i int, f field, code func(expr string, t types.Type, omitted bool) error) error {
+	g.fieldName = f.v.Name()
+	expr := "v." + f.v.Name()
+
+	if i > 0 {
+		g.printf("\n")
+	}
+	g.printf("// %s\n", f.v.Name())
+
+	if !f.v.Exported() {
+		g.printf("if buf.Unexported() {\n")
+		defer g.printf("}\n")
+	}
+
+	if f.omitEmpty {
+		_, _, ok := zeroValue(f.v.Type())
+		if !ok {
+			return fmt.Errorf("%s.%s: omitempty is not supported for %s", g.typeName, f.v.Name(), g.typeString(f.v.Type()))
+		}
+	}
+
+	return code(expr, f.v.Type(), f.omitEmpty)
+}
+
+// Get expression checking zero value and zero value itself.
+// Only types where == matches reflect.Value.IsZero are supported.
+func zeroValue(t types.Type) (check string, zero string, ok bool) {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsBoolean != 0:
+			return "== false", "false", true
+		case u.Info()&types.IsInteger != 0:
+			return "== 0", "0", true
+		case u.Info()&types.IsString != 0:
+			return `== ""`, `""`, true
+		}
+	case *types.Pointer, *types.Slice, *types.Map, *types.Interface, *types.Chan, *types.Signature:
+		return "== nil", "nil", true
+	}
+	return "", "", false
+}
+
+// Get type name as used in generated package.
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		return p.Name()
+	})
+}
+
+// Check if type (or pointer to it) has custom encoding in bitbox.
+func hasCustomEncoding(t types.Type) bool {
+	ms := types.NewMethodSet(types.NewPointer(t))
+	has := func(name string) bool {
+		return ms.Lookup(nil, name) != nil
+	}
+
+	return has("EncodeBitbox") || has("DecodeBitbox") ||
+		(has("MarshalBinary") && has("UnmarshalBinary")) ||
+		(has("MarshalText") && has("UnmarshalText"))
+}
+
+// Choose codec for type.
+func (g *generator) codecOf(t types.Type) codec {
+	t = types.Unalias(t)
+
+	if named, ok := t.(*types.Named); ok {
+		if g.types[named.Obj()] {
+			return codecGenerated
+		}
+
+		// Types from other packages (time, math/big, ...) can have
+		// special encoding, leave them to bitbox.
+		if named.Obj().Pkg() != g.pkg || named.TypeArgs().Len() > 0 {
+			return codecNested
+		}
+
+		if hasCustomEncoding(named) {
+			return codecNested
+		}
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch u.Kind() {
+		case types.Int:
+			return codecInt
+		case types.Uint:
+			return codecUint
+		case types.String:
+			return codecString
+		case types.Bool, types.Int8, types.Int16, types.Int32, types.Int64,
+			types.Uint8, types.Uint16, types.Uint32, types.Uint64, types.Uintptr,
+			types.Float32, types.Float64, types.Complex64, types.Complex128:
+			return codecFixed
+		}
+
+	case *types.Pointer:
+		if g.codecOf(u.Elem()) != codecNested {
+			return codecPointer
+		}
+
+	case *types.Slice:
+		switch g.codecOf(u.Elem()) {
+		case codecNested:
+		case codecFixed:
+			return codecFixedSlice
+		default:
+			return codecSlice
+		}
+
+	case *types.Array:
+		switch g.codecOf(u.Elem()) {
+		case codecNested:
+		case codecFixed:
+			return codecFixed
+		default:
+			return codecArray
+		}
+	}
+	return codecNested
+}
+
+// Get address of expression, dereferenced pointers are used directly.
+func addr(expr string) string {
+	if strings.HasPrefix(expr, "(*") && strings.HasSuffix(expr, ")") {
+		return expr[2 : len(expr)-1]
+	}
+	return "&" + expr
+}
+
+// Convert expression to type, when its type is different.
+func (g *generator) convert(expr string, t types.Type, to string) string {
+	if g.typeString(t) == to {
+		return expr
+	}
+
+	if strings.HasPrefix(to, "*") {
+		to = "(" + to + ")"
+	}
+	return to + "(" + expr + ")"
+}
+
+// Get element type of pointer, slice or array.
+func elemOf(t types.Type) types.Type {
+	return t.Underlying().(interface{ Elem() types.Type }).Elem()
+}
+
+// Get name of loop index at current depth.
+func (g *generator) index() string {
+	return "i" + strconv.Itoa(g.depth)
+}
+
+func (g *generator) encode(expr string, t types.Type, omitted bool) error {
+	if omitted {
+		check, _, _ := zeroValue(t)
+
+		g.printf("if %s %s {\nbitbox.WriteFlag(buf, false)\n} else {\nbitbox.WriteFlag(buf, true)\n", expr, check)
+		defer g.printf("}\n")
+	}
+
+	switch g.codecOf(t) {
+	case codecGenerated:
+		g.printf("if err := %s.EncodeBitbox(buf); err != nil {\nreturn err\n}\n", strings.TrimPrefix(addr(expr), "&"))
+	case codecFixed:
+		g.printf("bitbox.WriteFixed(buf, %s)\n", addr(expr))
+	case codecInt:
+		g.printf("bitbox.WriteInt(buf, %s)\n", expr)
+	case codecUint:
+		g.printf("bitbox.WriteUint(buf, %s)\n", expr)
+	case codecString:
+		g.printf("bitbox.WriteString(buf, %s)\n", g.convert(expr, t, "string"))
+	case codecFixedSlice:
+		g.printf("bitbox.WriteSlice(buf, %s)\n", g.convert(expr, t, "[]"+g.typeString(elemOf(t))))
+
+	case codecSlice, codecArray:
+		if g.codecOf(t) == codecSlice {
+			g.printf("bitbox.WriteLen(buf, len(%s))\n", expr)
+		}
+
+		i := g.index()
+		g.depth++
+
+		g.printf("for %s := range %s {\n", i, expr)
+		g.encode(expr+"["+i+"]", elemOf(t), false)
+		g.printf("}\n")
+
+		g.depth--
+
+	case codecPointer:
+		g.printf("bitbox.WriteFlag(buf, %s != nil)\n", expr)
+		g.printf("if %s != nil {\n", expr)
+		g.encode("(*"+expr+")", elemOf(t), false)
+		g.printf("}\n")
+
+	default:
+		g.printf("if err := bitbox.EncodeNested(buf, %s); err != nil {\nreturn err\n}\n", addr(expr))
+	}
+	return nil
+}
+
+// Return from decode with field context, same as bitbox.Decode.
+func (g *generator) fail() string {
+	return fmt.Sprintf("return fmt.Errorf(%q, err)", g.typeName+"."+g.fieldName+": %w")
+}
+
+// Generate call returning only error.
+func (g *generator) check(call string, args ...any) {
+	g.printf("if err := "+call+"; err != nil {\n%s\n}\n", append(args, g.fail())...)
+}
+
+func (g *generator) decode(expr string, t types.Type, omitted bool) error {
+	if omitted {
+		_, zero, _ := zeroValue(t)
+
+		g.printf("if set, err := bitbox.ReadFlag(buf); err != nil {\n%s\n} else if !set {\n%s = %s\n} else {\n", g.fail(), expr, zero)
+		defer g.printf("}\n")
+	}
+
+	switch g.codecOf(t) {
+	case codecGenerated:
+		g.check("bitbox.DecodeUnmarshaler(buf, %s)", addr(expr))
+	case codecFixed:
+		g.check("bitbox.ReadFixed(buf, %s)", addr(expr))
+	case codecInt:
+		g.check("bitbox.ReadInt(buf, %s)", addr(expr))
+	case codecUint:
+		g.check("bitbox.ReadUint(buf, %s)", addr(expr))
+	case codecString:
+		g.check("bitbox.ReadString(buf, %s)", g.convert(addr(expr), types.NewPointer(t), "*string"))
+	case codecFixedSlice:
+		g.check("bitbox.ReadSlice(buf, %s)", g.convert(addr(expr), types.NewPointer(t), "*[]"+g.typeString(elemOf(t))))
+
+	case codecSlice:
+		typ := g.typeString(t)
+
+		g.printf("if n, err := bitbox.ReadLen[%s](buf); err != nil {\n%s\n} else {\n", typ, g.fail())
+		g.printf("if cap(%s) < n {\n%s = make(%s, n)\n} else {\n%s = %s[:n]\n}\n", expr, expr, typ, expr, expr)
+
+		g.decodeElems(expr, elemOf(t))
+		g.printf("}\n")
+
+	case codecArray:
+		g.decodeElems(expr, elemOf(t))
+
+	case codecPointer:
+		elem := elemOf(t)
+
+		g.printf("if set, err := bitbox.ReadFlag(buf); err != nil {\n%s\n} else if !set {\n%s = nil\n} else {\n", g.fail(), expr)
+		g.printf("if %s == nil {\n%s = new(%s)\n}\n", expr, expr, g.typeString(elem))
+
+		g.decode("(*"+expr+")", elem, false)
+		g.printf("}\n")
+
+	default:
+		g.check("bitbox.DecodeNested(buf, %s)", addr(expr))
+	}
+	return nil
+}
+
+// Decode every element of slice or array.
+func (g *generator) decodeElems(expr string, elem types.Type) {
+	i := g.index()
+	g.depth++
+
+	g.printf("for %s := range %s {\n", i, expr)
+	g.decode(expr+"["+i+"]", elem, false)
+	g.printf("}\n")
+
+	g.depth--
+}
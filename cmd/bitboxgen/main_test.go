@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/datagentleman/bitbox"
+)
+
+// Generated example must match committed file, run go generate
+// in internal/example after changing the generator.
+func TestGolden(t *testing.T) {
+	dir := filepath.Join("internal", "example")
+
+	src, err := generate(dir, []string{"Tx", "Header", "Block"}, "bitbox_gen.go")
+	bitbox.AssertEqual(t, nil, err)
+
+	golden, err := os.ReadFile(filepath.Join(dir, "bitbox_gen.go"))
+	bitbox.AssertEqual(t, nil, err)
+
+	bitbox.Assert(t, true, bytes.Equal(golden, src))
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		typ  string
+		err  string
+	}{
+		{name: "missing", src: "type A struct{ X int }", typ: "B", err: "not found"},
+		{name: "not_struct", src: "type A []int", typ: "A", err: "not a struct"},
+		{name: "generic", src: "type A[T any] struct{ X T }", typ: "A", err: "not a struct"},
+		{name: "empty", src: "type A struct{ X int `bitbox:\"-\"` }", typ: "A", err: "no encoded fields"},
+		{name: "blank", src: "type A struct{ _ int }", typ: "A", err: "blank fields"},
+		{name: "option", src: "type A struct{ X int `bitbox:\",other\"` }", typ: "A", err: "unknown option"},
+		{name: "position", src: "type A struct{ X int `bitbox:\"x\"` }", typ: "A", err: "invalid position"},
+		{name: "same_position", src: "type A struct{ X, Y int `bitbox:\"1\"` }", typ: "A", err: "same position"},
+		{name: "omitempty", src: "type A struct{ X float64 `bitbox:\",omitempty\"` }", typ: "A", err: "omitempty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\n"+tt.src+"\n"), 0o644)
+			bitbox.AssertEqual(t, nil, err)
+
+			_, err = generate(dir, []string{tt.typ}, "bitbox_gen.go")
+			bitbox.Assert(t, true, err != nil && strings.Contains(err.Error(), tt.err))
+		})
+	}
+}
@@ -1,13 +1,24 @@
 package bitbox
 
 import (
+	"math/big"
 	"reflect"
+	"strconv"
+	"time"
 	"unsafe"
 )
 
 // Decode objects
 func Decode(buf *Buffer, objects ...any) error {
+	buf.begin()
+
 	for _, obj := range objects {
+		val := reflect.ValueOf(obj)
+
+		if !isPointer(val.Kind()) || val.IsNil() {
+			return invalidValue(val)
+		}
+
 		// Fast path - type cast
 		handled, err := decodeFixed(buf, obj)
 		if err != nil {
@@ -18,15 +29,27 @@ func Decode(buf *Buffer, objects ...any) error {
 			continue
 		}
 
+		// Fast path handles only builtin and standard library types, they
+		// can't implement Unmarshaler. Custom decoding is checked after it.
+		if u, ok := obj.(Unmarshaler); ok {
+			err := decodeUnmarshaler(buf, u)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
 		// Slow path - reflections
-		val := reflect.ValueOf(obj)
+		// Top level pointers have no flag, nil pointers are allocated.
+		val = val.Elem()
 
-		if !isPointer(val.Kind()) || val.IsNil() || !val.IsValid() {
-			return invalidValue(val)
+		for isPointer(val.Kind()) {
+			if val.IsNil() {
+				val.Set(reflect.New(val.Type().Elem()))
+			}
+			val = val.Elem()
 		}
 
-		val = reflect.Indirect(val)
-
 		isPOD := false
 		err = decode(buf, val, isPOD)
 		if err != nil {
@@ -37,95 +60,265 @@ func Decode(buf *Buffer, objects ...any) error {
 }
 
 func decode(buf *Buffer, val reflect.Value, isPOD bool) error {
+	return decodeValue(buf, buf.planOf(val.Type()), val, isPOD)
+}
+
+// Decode value using plan of its type.
+func decodeValue(buf *Buffer, p *plan, val reflect.Value, isPOD bool) error {
 	var err error
 
-	switch val.Kind() {
+	switch p.builtin {
+	case builtinTime:
+		return decodeTime(buf, (*time.Time)(valuePointer(val)))
+	case builtinBigInt:
+		return decodeBigInt(buf, (*big.Int)(valuePointer(val)))
+	case builtinBigRat:
+		return decodeBigRat(buf, (*big.Rat)(valuePointer(val)))
+	case builtinBigFloat:
+		return decodeBigFloat(buf, (*big.Float)(valuePointer(val)))
+	}
+
+	if p.custom != 0 {
+		if u, ok := asUnmarshaler(p, val); ok {
+			return decodeUnmarshaler(buf, u)
+		}
+
+		if ok, err := decodeBinary(buf, p, val); ok {
+			return err
+		}
+	}
+
+	switch p.kind {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.Struct:
+		err = buf.enter()
+		if err == nil {
+			err = decodeNested(buf, p, val, isPOD)
+		}
+
+		buf.leave()
+		return err
+	}
+
+	switch p.kind {
 	case
 		reflect.Bool, reflect.Uintptr, reflect.Int8, reflect.Int16,
 		reflect.Int32, reflect.Int64, reflect.Uint8, reflect.Uint16,
 		reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64,
 		reflect.Complex64, reflect.Complex128:
 
-		size := int(val.Type().Size())
-		buf.Read(toBytes(val, size))
+		err = buf.readTyped(toBytes(val, p.size), p.typ)
 
-	case reflect.Slice:
-		// Fast path for named bytes
-		if val.Type().Elem().Kind() == reflect.Uint8 {
-			l := uint32(0)
-			buf.Decode(&l)
+	// Int and uint are always read as 64 bit values.
+	case reflect.Int:
+		v := int64(0)
+		err = readFixed(buf, &v)
+		if err != nil {
+			return err
+		}
 
-			ensureLen(val, int(l))
-			buf.Read(toBytes(val, int(l)))
-			return nil
+		if intOverflows(v, strconv.IntSize) {
+			return overflow(p.typ, v)
 		}
+		val.SetInt(v)
 
-		err = decodeSlice(buf, val, isPOD)
-	case reflect.Array:
-		err = decodeArray(buf, val, isPOD)
-	case reflect.Struct:
-		err = decodeStruct(buf, val, isPOD)
+	case reflect.Uint:
+		v := uint64(0)
+		err = readFixed(buf, &v)
+		if err != nil {
+			return err
+		}
+
+		if uintOverflows(v, strconv.IntSize) {
+			return overflow(p.typ, v)
+		}
+		val.SetUint(v)
+
+	case reflect.Pointer:
+		err = decodePointer(buf, p, val, isPOD)
+	case reflect.Interface:
+		err = decodeInterface(buf, val, isPOD)
 	case reflect.String:
-		l := uint32(0)
-		buf.Decode(&l)
-		b, err := buf.Next(int(l))
+		l, err := readCount(buf, p.typ, 1, 1)
 		if err != nil {
 			return err
 		}
-		val.SetString(string(b))
+
+		b, err := buf.Next(l)
+		if err != nil {
+			return truncated(p.typ, err)
+		}
+		val.SetString(buf.toString(b))
 	default:
 		return invalidValue(val)
 	}
 	return err
 }
 
+// Decode values counted in MaxDepth limit. Kept apart from decodeValue,
+// so entered depth is left without defer.
+func decodeNested(buf *Buffer, p *plan, val reflect.Value, isPOD bool) error {
+	switch p.kind {
+	case reflect.Slice:
+		// Fast path for named bytes
+		if p.elem.kind == reflect.Uint8 && p.elem.copyable {
+			l, err := readCount(buf, p.typ, 1, 1)
+			if err != nil {
+				return err
+			}
+
+			if buf.aliasing() && l > 0 {
+				data, err := buf.Next(l)
+				if err != nil {
+					return truncated(p.typ, err)
+				}
+
+				val.SetBytes(data[:l:l])
+				return nil
+			}
+
+			ensureLen(val, l)
+			return buf.readTyped(toBytes(val, l), p.elem.typ)
+		}
+
+		return decodeSlice(buf, p, val, isPOD)
+	case reflect.Map:
+		return decodeMap(buf, p, val, isPOD)
+	case reflect.Array:
+		return decodeArray(buf, p, val, isPOD)
+	default:
+		return decodeStruct(buf, p, val, isPOD)
+	}
+}
+
 // Decode structs.
-func decodeStruct(buf *Buffer, val reflect.Value, isPOD bool) error {
+func decodeStruct(buf *Buffer, p *plan, val reflect.Value, isPOD bool) error {
 	if isPOD {
-		size := int(val.Type().Size())
-		buf.Read(toBytes(val, size))
-		return nil
+		return buf.readTyped(toBytes(val, p.size), p.typ)
 	}
 
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
+	if p.err != nil {
+		return p.err
+	}
+
+	for i := 0; i < len(p.fields); i++ {
+		f := &p.fields[i]
+
+		if f.run > 0 {
+			dst := unsafe.Slice((*byte)(unsafe.Add(unsafe.Pointer(val.UnsafeAddr()), f.offset)), f.runSize)
+
+			err := buf.readRun(dst, p.fields[i:i+f.run])
+			if err != nil {
+				return fieldError(p.typ, f.index, err)
+			}
+
+			i += f.run - 1
+			continue
+		}
 
-		if field.Kind() == reflect.Pointer {
-			ptrFlag := uint8(1)
-			buf.Read(ToBytes(&ptrFlag))
+		if f.flatPointer {
+			ptr := (*unsafe.Pointer)(unsafe.Add(unsafe.Pointer(val.UnsafeAddr()), f.offset))
 
-			if ptrFlag == 0 {
-				field.Set(reflect.Zero(field.Type()))
+			err := decodeFlatPointer(buf, ptr, f.plan.elem)
+			if err != nil {
+				return fieldError(p.typ, f.index, err)
+			}
+			continue
+		}
+
+		field := val.Field(f.index)
+
+		if !f.exported {
+			if !buf.opts.unexported {
 				continue
 			}
+			field = exposed(field)
+		}
+
+		if f.omitEmpty {
+			flag := uint8(1)
 
-			if field.IsNil() {
-				field.Set(reflect.New(field.Type().Elem()))
+			err := readFixed(buf, &flag)
+			if err != nil {
+				return fieldError(p.typ, f.index, err)
 			}
 
-			field = field.Elem()
+			if flag == 0 {
+				field.SetZero()
+				continue
+			}
 		}
 
-		err := decode(buf, field, isPOD)
+		err := decodeValue(buf, f.plan, field, isPOD)
 		if err != nil {
-			return err
+			return fieldError(p.typ, f.index, err)
 		}
 	}
 	return nil
 }
 
-// Decode arrays.
-func decodeArray(buf *Buffer, val reflect.Value, isPOD bool) error {
-	elem := val.Type().Elem()
-	total := val.Len() * int(elem.Size())
+// Decode pointer flag and pointed value.
+// Nil pointers are allocated, existing ones are reused.
+func decodePointer(buf *Buffer, p *plan, val reflect.Value, isPOD bool) error {
+	ptrFlag := uint8(1)
+
+	err := readFixed(buf, &ptrFlag)
+	if err != nil {
+		return err
+	}
 
-	if isFixedType(elem.Kind()) {
-		buf.Read(toBytes(val, int(total)))
+	if ptrFlag == 0 {
+		val.SetZero()
 		return nil
 	}
 
+	if val.IsNil() {
+		val.Set(reflect.New(p.elem.typ))
+	}
+	return decodeValue(buf, p.elem, val.Elem(), isPOD)
+}
+
+// Decode pointer to flat value, reading pointed memory directly.
+// Flat arrays and structs still count as nested values.
+func decodeFlatPointer(buf *Buffer, ptr *unsafe.Pointer, elem *plan) error {
+	ptrFlag := uint8(1)
+
+	err := readFixed(buf, &ptrFlag)
+	if err != nil {
+		return err
+	}
+
+	if ptrFlag == 0 {
+		*ptr = nil
+		return nil
+	}
+
+	if *ptr == nil {
+		*ptr = reflect.New(elem.typ).UnsafePointer()
+	}
+
+	if elem.kind == reflect.Array || elem.kind == reflect.Struct {
+		err = buf.enter()
+		if err == nil {
+			err = buf.readTyped(unsafe.Slice((*byte)(*ptr), elem.size), elem.typ)
+		}
+
+		buf.leave()
+		return err
+	}
+	return buf.readTyped(unsafe.Slice((*byte)(*ptr), elem.size), elem.typ)
+}
+
+// Decode arrays.
+func decodeArray(buf *Buffer, p *plan, val reflect.Value, isPOD bool) error {
+	elem := p.elem
+
+	// Nested arrays and arrays of structs are copied at once.
+	if elem.flat || (isPOD && elem.podFlat) {
+		return buf.readTyped(toBytes(val, p.size), elem.typ)
+	}
+
 	for i := 0; i < val.Len(); i++ {
-		err := decode(buf, val.Index(i), isPOD)
+		err := decodeValue(buf, elem, val.Index(i), isPOD)
 		if err != nil {
 			return err
 		}
@@ -134,42 +327,130 @@ func decodeArray(buf *Buffer, val reflect.Value, isPOD bool) error {
 }
 
 // Decode slices.
-func decodeSlice(buf *Buffer, val reflect.Value, isPOD bool) error {
-	num := uint32(0)
-	buf.Decode(&num)
+func decodeSlice(buf *Buffer, p *plan, val reflect.Value, isPOD bool) error {
+	elem := p.elem
 
-	ensureLen(val, int(num))
-	elem := val.Type().Elem()
+	num, err := readCount(buf, p.typ, elem.minSize, elem.size)
+	if err != nil {
+		return err
+	}
 
-	if isPOD && elem.Kind() == reflect.Struct {
-		tsize := uint32(elem.Size())
-		total := int(num * tsize)
+	ensureLen(val, num)
 
-		buf.Read(toBytes(val, total))
-		return nil
+	if elem.flat || (isPOD && elem.podFlat) {
+		return buf.readTyped(toBytes(val, num*elem.size), elem.typ)
 	}
 
-	if isFixedType(elem.Kind()) {
-		tsize := uint32(elem.Size())
-		total := int(num * tsize)
-
-		buf.Read(toBytes(val, total))
+	// Zero sized elements have no data, don't loop over them.
+	if elem.size == 0 {
 		return nil
 	}
 
 	for i := 0; i < val.Len(); i++ {
-		err := decode(buf, val.Index(i), isPOD)
+		err := decodeValue(buf, elem, val.Index(i), isPOD)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decode maps.
+// Existing map is cleared and reused, nil map stays nil when there are no entries.
+func decodeMap(buf *Buffer, p *plan, val reflect.Value, isPOD bool) error {
+	size := p.key.minSize + p.elem.minSize
+
+	num, err := readCount(buf, p.typ, size, p.key.size+p.elem.size)
+	if err != nil {
+		return err
+	}
+
+	// Map with zero sized keys can't have more than one entry.
+	if size == 0 && num > 1 {
+		return invalidValue(val)
+	}
+
+	if val.IsNil() {
+		if num == 0 {
+			return nil
+		}
+		val.Set(reflect.MakeMapWithSize(p.typ, num))
+	} else {
+		val.Clear()
+	}
+
+	// Entries are decoded into values reused for whole map.
+	// They are zeroed first, so pointers and slices are not shared.
+	key := reflect.New(p.key.typ).Elem()
+	elem := reflect.New(p.elem.typ).Elem()
+
+	for i := 0; i < num; i++ {
+		key.SetZero()
+		err = decodeValue(buf, p.key, key, isPOD)
+		if err != nil {
+			return err
+		}
+
+		elem.SetZero()
+		err = decodeValue(buf, p.elem, elem, isPOD)
+		if err != nil {
+			return err
+		}
+
+		val.SetMapIndex(key, elem)
+	}
+	return nil
+}
+
+// Decode maps with basic keys and values.
+func decodeFixedMap[K comparable, V any](buf *Buffer, out *map[K]V) error {
+	typ := reflect.TypeFor[map[K]V]()
+	key, elem := typ.Key(), typ.Elem()
+
+	n, err := readCount(buf, typ, minSize(key)+minSize(elem), int(key.Size()+elem.Size()))
+	if err != nil {
+		return err
+	}
+
+	if *out == nil {
+		if n == 0 {
+			return nil
+		}
+		*out = make(map[K]V, n)
+	} else {
+		clear(*out)
+	}
+
+	for i := 0; i < n; i++ {
+		var k K
+		var v V
+
+		_, err = decodeFixed(buf, &k)
 		if err != nil {
 			return err
 		}
+
+		_, err = decodeFixed(buf, &v)
+		if err != nil {
+			return err
+		}
+
+		(*out)[k] = v
 	}
 	return nil
 }
 
 func decodeFixedSlice[T any](buf *Buffer, out *[]T) error {
-	l := uint32(0)
-	buf.Decode(&l)
-	n := int(l)
+	if b, ok := any(out).(*[]byte); ok && buf.aliasing() {
+		return decodeAliasedBytes(buf, b)
+	}
+
+	size := int(unsafe.Sizeof(*new(T)))
+
+	n, err := readCount(buf, reflect.TypeFor[[]T](), size, size)
+	if err != nil {
+		return err
+	}
 
 	if cap(*out) < n {
 		*out = make([]T, n)
@@ -177,50 +458,111 @@ func decodeFixedSlice[T any](buf *Buffer, out *[]T) error {
 		*out = (*out)[:n]
 	}
 
-	total := n * int(unsafe.Sizeof(*new(T)))
+	total := n * size
 	s := unsafe.SliceData(*out)
 	b := unsafe.Slice((*byte)(unsafe.Pointer(s)), total)
 
-	buf.Read(b)
+	return buf.readTyped(b, reflect.TypeFor[T]())
+}
+
+// Decode byte slice pointing into buffer data, see SetZeroCopy.
+func decodeAliasedBytes(buf *Buffer, out *[]byte) error {
+	typ := reflect.TypeFor[[]byte]()
+
+	n, err := readCount(buf, typ, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	data, err := buf.Next(n)
+	if err != nil {
+		return truncated(typ, err)
+	}
+
+	// Empty slices are decoded the same way as without aliasing.
+	if n == 0 {
+		*out = (*out)[:0]
+		return nil
+	}
+
+	*out = data[:n:n]
 	return nil
 }
 
-func DecodePOD(buf *Buffer, object any) error {
-	if object == nil {
-		return invalidValue(reflect.ValueOf(object))
+// Decode int/uint slices, they are copied directly only on 64 bit platforms.
+func decodeIntSlice[T int | uint](buf *Buffer, out *[]T) error {
+	if strconv.IntSize == 64 {
+		return decodeFixedSlice(buf, out)
 	}
 
-	handled, err := decodeFixed(buf, object)
+	n, err := readCount(buf, reflect.TypeFor[[]T](), 8, int(unsafe.Sizeof(T(0))))
 	if err != nil {
 		return err
 	}
 
-	if handled {
-		return nil
+	if cap(*out) < n {
+		*out = make([]T, n)
+	} else {
+		*out = (*out)[:n]
+	}
+
+	for i := 0; i < n; i++ {
+		_, err = decodeFixed(buf, &(*out)[i])
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// Decode POD written by EncodePOD. Types are checked the same way as in EncodePOD.
+func DecodePOD(buf *Buffer, object any) error {
+	return decodePOD(buf, object, true)
+}
+
+// Decode POD without checking its type, for types already known to be POD.
+// Pointers and slices would be read from input as is.
+func DecodePODUnchecked(buf *Buffer, object any) error {
+	return decodePOD(buf, object, false)
+}
 
+func decodePOD(buf *Buffer, object any, check bool) error {
 	val := reflect.ValueOf(object)
 
-	if !isPointer(val.Kind()) || val.IsNil() || !val.IsValid() {
+	if !isPointer(val.Kind()) || val.IsNil() {
 		return invalidValue(val)
 	}
 
-	val = reflect.Indirect(val)
+	p := buf.planOf(val.Type().Elem())
+
+	if check && p.pod != nil {
+		return p.pod
+	}
+
+	buf.begin()
+
+	handled, err := decodeFixed(buf, object)
+	if err != nil {
+		return err
+	}
 
-	if val.Kind() == reflect.Struct {
-		size := int(val.Type().Size())
-		buf.Read(toBytes(val, size))
+	if handled {
 		return nil
 	}
 
-	err = decode(buf, val, true)
-	return err
+	val = val.Elem()
+
+	if p.kind == reflect.Struct {
+		return buf.readTyped(toBytes(val, p.size), p.typ)
+	}
+	return decodeValue(buf, p, val, true)
 }
 
-func decodeFixedSlice2D[T any](buf *Buffer, out *[][]T) {
-	l := uint32(0)
-	buf.Decode(&l)
-	n := int(l)
+func decodeFixedSlice2D[T any](buf *Buffer, out *[][]T) error {
+	n, err := readCount(buf, reflect.TypeFor[[][]T](), 4, int(unsafe.Sizeof([]T(nil))))
+	if err != nil {
+		return err
+	}
 
 	if cap(*out) < n {
 		*out = make([][]T, n)
@@ -229,113 +571,195 @@ func decodeFixedSlice2D[T any](buf *Buffer, out *[][]T) {
 	}
 
 	for i := 0; i < n; i++ {
-		decodeFixedSlice(buf, &(*out)[i])
+		err = decodeFixedSlice(buf, &(*out)[i])
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func decodeFixed(buf *Buffer, obj any) (bool, error) {
+	var err error
+
 	switch val := obj.(type) {
 
 	// Basic Pointers
+	case *int:
+		v := int64(0)
+		err = readFixed(buf, &v)
+		if err != nil {
+			return true, err
+		}
+
+		if intOverflows(v, strconv.IntSize) {
+			return true, overflow(reflect.TypeOf(*val), v)
+		}
+		*val = int(v)
+
+	case *uint:
+		v := uint64(0)
+		err = readFixed(buf, &v)
+		if err != nil {
+			return true, err
+		}
+
+		if uintOverflows(v, strconv.IntSize) {
+			return true, overflow(reflect.TypeOf(*val), v)
+		}
+		*val = uint(v)
+
 	case *int8:
-		buf.Read(ToBytes(val))
+		err = readFixed(buf, val)
 	case *int16:
-		buf.Read(ToBytes(val))
+		err = readFixed(buf, val)
 	case *int32:
-		buf.Read(ToBytes(val))
+		err = readFixed(buf, val)
 	case *int64:
-		buf.Read(ToBytes(val))
+		err = readFixed(buf, val)
 	case *uint8:
-		buf.Read(ToBytes(val))
+		err = readFixed(buf, val)
 	case *uint16:
-		buf.Read(ToBytes(val))
+		err = readFixed(buf, val)
 	case *uint32:
-		buf.Read(ToBytes(val))
+		err = readFixed(buf, val)
 	case *uint64:
-		buf.Read(ToBytes(val))
+		err = readFixed(buf, val)
 	case *float32:
-		buf.Read(ToBytes(val))
+		err = readFixed(buf, val)
 	case *float64:
-		buf.Read(ToBytes(val))
+		err = readFixed(buf, val)
 	case *complex64:
-		buf.Read(ToBytes(val))
+		err = readFixed(buf, val)
 	case *complex128:
-		buf.Read(ToBytes(val))
+		err = readFixed(buf, val)
 	case *uintptr:
-		buf.Read(ToBytes(val))
+		err = readFixed(buf, val)
 	case *bool:
-		buf.Read(ToBytes(val))
+		err = readFixed(buf, val)
 
+	case *[]int:
+		return true, decodeIntSlice(buf, val)
+	case *[]uint:
+		return true, decodeIntSlice(buf, val)
 	case *[]byte:
-		decodeFixedSlice(buf, val)
+		err = decodeFixedSlice(buf, val)
 	case *[]int8:
-		decodeFixedSlice(buf, val)
+		err = decodeFixedSlice(buf, val)
 	case *[]int16:
-		decodeFixedSlice(buf, val)
+		err = decodeFixedSlice(buf, val)
 	case *[]int32:
-		decodeFixedSlice(buf, val)
+		err = decodeFixedSlice(buf, val)
 	case *[]int64:
-		decodeFixedSlice(buf, val)
+		err = decodeFixedSlice(buf, val)
 	case *[]uint16:
-		decodeFixedSlice(buf, val)
+		err = decodeFixedSlice(buf, val)
 	case *[]uint32:
-		decodeFixedSlice(buf, val)
+		err = decodeFixedSlice(buf, val)
 	case *[]uint64:
-		decodeFixedSlice(buf, val)
+		err = decodeFixedSlice(buf, val)
 	case *[]float32:
-		decodeFixedSlice(buf, val)
+		err = decodeFixedSlice(buf, val)
 	case *[]float64:
-		decodeFixedSlice(buf, val)
+		err = decodeFixedSlice(buf, val)
 	case *[]complex64:
-		decodeFixedSlice(buf, val)
+		err = decodeFixedSlice(buf, val)
 	case *[]complex128:
-		decodeFixedSlice(buf, val)
+		err = decodeFixedSlice(buf, val)
 	case *[]uintptr:
-		decodeFixedSlice(buf, val)
+		err = decodeFixedSlice(buf, val)
 	case *[]bool:
-		decodeFixedSlice(buf, val)
+		err = decodeFixedSlice(buf, val)
 
 	case *[][]byte:
-		decodeFixedSlice2D(buf, val)
+		err = decodeFixedSlice2D(buf, val)
 	case *[][]int8:
-		decodeFixedSlice2D(buf, val)
+		err = decodeFixedSlice2D(buf, val)
 	case *[][]int16:
-		decodeFixedSlice2D(buf, val)
+		err = decodeFixedSlice2D(buf, val)
 	case *[][]int32:
-		decodeFixedSlice2D(buf, val)
+		err = decodeFixedSlice2D(buf, val)
 	case *[][]int64:
-		decodeFixedSlice2D(buf, val)
+		err = decodeFixedSlice2D(buf, val)
 	case *[][]uint16:
-		decodeFixedSlice2D(buf, val)
+		err = decodeFixedSlice2D(buf, val)
 	case *[][]uint32:
-		decodeFixedSlice2D(buf, val)
+		err = decodeFixedSlice2D(buf, val)
 	case *[][]uint64:
-		decodeFixedSlice2D(buf, val)
+		err = decodeFixedSlice2D(buf, val)
 	case *[][]float32:
-		decodeFixedSlice2D(buf, val)
+		err = decodeFixedSlice2D(buf, val)
 	case *[][]float64:
-		decodeFixedSlice2D(buf, val)
+		err = decodeFixedSlice2D(buf, val)
 	case *[][]complex64:
-		decodeFixedSlice2D(buf, val)
+		err = decodeFixedSlice2D(buf, val)
 	case *[][]complex128:
-		decodeFixedSlice2D(buf, val)
+		err = decodeFixedSlice2D(buf, val)
 	case *[][]uintptr:
-		decodeFixedSlice2D(buf, val)
+		err = decodeFixedSlice2D(buf, val)
 	case *[][]bool:
-		decodeFixedSlice2D(buf, val)
+		err = decodeFixedSlice2D(buf, val)
 
 	// String
 	case *string:
-		l := uint32(0)
-		buf.Decode(&l)
-		b, err := buf.Next(int(l))
+		l, err := readCount(buf, reflect.TypeOf(*val), 1, 1)
+		if err != nil {
+			return true, err
+		}
+
+		b, err := buf.Next(l)
 		if err != nil {
-			return false, err
+			return true, truncated(reflect.TypeOf(*val), err)
+		}
+		*val = buf.toString(b)
+
+	// Time
+	case *time.Time:
+		err = decodeTime(buf, val)
+	case *time.Duration:
+		err = readFixed(buf, val)
+	case *[]time.Duration:
+		err = decodeFixedSlice(buf, val)
+
+	// Big numbers
+	case *big.Int:
+		err = decodeBigInt(buf, val)
+	case **big.Int:
+		if *val == nil {
+			*val = new(big.Int)
+		}
+		err = decodeBigInt(buf, *val)
+	case *big.Rat:
+		err = decodeBigRat(buf, val)
+	case **big.Rat:
+		if *val == nil {
+			*val = new(big.Rat)
+		}
+		err = decodeBigRat(buf, *val)
+	case *big.Float:
+		err = decodeBigFloat(buf, val)
+	case **big.Float:
+		if *val == nil {
+			*val = new(big.Float)
 		}
-		*val = string(b)
+		err = decodeBigFloat(buf, *val)
+
+	// Maps
+	case *map[string]string:
+		return true, decodeFixedMap(buf, val)
+	case *map[string]uint64:
+		return true, decodeFixedMap(buf, val)
+	case *map[string][]byte:
+		return true, decodeFixedMap(buf, val)
+	case *map[uint64]uint64:
+		return true, decodeFixedMap(buf, val)
+	case *map[uint64][]byte:
+		return true, decodeFixedMap(buf, val)
+	case *map[uint64]string:
+		return true, decodeFixedMap(buf, val)
 
 	default:
 		return false, nil
 	}
-	return true, nil
+	return true, err
 }
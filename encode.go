@@ -1,26 +1,50 @@
 package bitbox
 
 import (
+	"bytes"
+	"math/big"
 	"reflect"
+	"slices"
+	"time"
 	"unsafe"
 )
 
 func Encode(buf *Buffer, objects ...any) error {
 	for _, obj := range objects {
+		val := reflect.ValueOf(obj)
+
+		// Nil pointers are skipped.
+		if isPointer(val.Kind()) && val.IsNil() {
+			continue
+		}
+
 		// Fast path - type cast
 		if encodeFixed(buf, obj) {
 			continue
 		}
 
+		// Fast path handles only builtin and standard library types, they
+		// can't implement Marshaler. Custom encoding is checked after it.
+		if m, ok := obj.(Marshaler); ok {
+			err := encodeMarshaler(buf, m)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
 		// Slow path - reflections
-		val := reflect.ValueOf(obj)
-		val = reflect.Indirect(val)
+		// Top level pointers have no flag, nil pointers are skipped.
+		for isPointer(val.Kind()) && !val.IsNil() {
+			val = val.Elem()
+		}
 
-		if !val.IsValid() {
+		if !val.IsValid() || isPointer(val.Kind()) {
 			continue
 		}
 
-		err := encode(buf, val, false)
+		// Copy value passed directly once, so nested values are addressable.
+		err := encode(buf, addressable(val), false)
 		if err != nil {
 			return err
 		}
@@ -29,41 +53,172 @@ func Encode(buf *Buffer, objects ...any) error {
 }
 
 // Encode slices.
-func encodeSlice(buf *Buffer, val reflect.Value, isPOD bool) error {
-	elem := val.Type().Elem()
+func encodeSlice(buf *Buffer, p *plan, val reflect.Value, isPOD bool) error {
+	elem := p.elem
 
 	// write number of elements
 	count := uint32(val.Len())
-	buf.Write(ToBytes(&count))
+	writeFixed(buf, &count)
 
-	if isPOD && elem.Kind() == reflect.Struct {
-		size := elem.Size()
-		total := count * uint32(size)
+	if elem.flat || (isPOD && elem.podFlat) {
+		total := count * uint32(elem.size)
 
-		buf.Write(toBytes(val, int(total)))
+		buf.writePOD(toBytes(val, int(total)), elem)
 		return nil
 	}
 
-	if isFixedType(elem.Kind()) {
-		size := elem.Size()
-		total := count * uint32(size)
+	start := buf.written
 
-		buf.Write(toBytes(val, int(total)))
-		return nil
+	for i := 0; i < val.Len(); i++ {
+		err := encodeValue(buf, elem, val.Index(i), isPOD)
+		if err != nil {
+			return err
+		}
 	}
+	return checkEncoded(p.typ, val.Len(), elem.size, buf.written-start)
+}
 
-	for i := 0; i < val.Len(); i++ {
-		err := encode(buf, val.Index(i), isPOD)
+// Elements taking memory must be encoded at least as one byte, decoder
+// checks their count against remaining input before allocating them.
+func checkEncoded(typ reflect.Type, count int, size int, written int) error {
+	if count > 0 && size > 0 && written == 0 {
+		return noData(typ)
+	}
+	return nil
+}
+
+// Encode maps.
+// Number of entries is written first, then key/value pairs.
+func encodeMap(buf *Buffer, p *plan, val reflect.Value, isPOD bool) error {
+	if buf.opts.deterministic {
+		return encodeSortedMap(buf, p, val, isPOD)
+	}
+
+	count := uint32(val.Len())
+	writeFixed(buf, &count)
+
+	// Entries are copied into addressable values, reused for whole map.
+	key := reflect.New(p.key.typ).Elem()
+	elem := reflect.New(p.elem.typ).Elem()
+	start := buf.written
+
+	iter := val.MapRange()
+	for iter.Next() {
+		key.SetIterKey(iter)
+		elem.SetIterValue(iter)
+
+		err := encodeValue(buf, p.key, key, isPOD)
+		if err != nil {
+			return err
+		}
+
+		err = encodeValue(buf, p.elem, elem, isPOD)
 		if err != nil {
 			return err
 		}
 	}
+	return checkEncoded(p.typ, val.Len(), p.key.size+p.elem.size, buf.written-start)
+}
+
+// Encode maps with entries sorted by encoded key bytes.
+// Entries are encoded into scratch buffer first and then copied in order.
+func encodeSortedMap(buf *Buffer, p *plan, val reflect.Value, isPOD bool) error {
+	type entry struct {
+		key []byte
+		val []byte
+	}
+
+	tmp := buf.scratch()
+	offsets := make([]int, 0, val.Len()*2+1)
+	offsets = append(offsets, 0)
+
+	key := reflect.New(p.key.typ).Elem()
+	elem := reflect.New(p.elem.typ).Elem()
+
+	iter := val.MapRange()
+	for iter.Next() {
+		key.SetIterKey(iter)
+		elem.SetIterValue(iter)
+
+		err := encodeValue(tmp, p.key, key, isPOD)
+		if err != nil {
+			return err
+		}
+		offsets = append(offsets, len(tmp.data))
+
+		err = encodeValue(tmp, p.elem, elem, isPOD)
+		if err != nil {
+			return err
+		}
+		offsets = append(offsets, len(tmp.data))
+	}
+
+	err := checkEncoded(p.typ, val.Len(), p.key.size+p.elem.size, len(tmp.data))
+	if err != nil {
+		return err
+	}
+
+	// Slice data only after encoding, tmp.data could be reallocated.
+	entries := make([]entry, 0, val.Len())
+	for i := 0; i+2 < len(offsets); i += 2 {
+		entries = append(entries, entry{
+			key: tmp.data[offsets[i]:offsets[i+1]],
+			val: tmp.data[offsets[i+1]:offsets[i+2]],
+		})
+	}
+
+	slices.SortStableFunc(entries, func(a, b entry) int {
+		return bytes.Compare(a.key, b.key)
+	})
+
+	// Different keys with the same encoding (NaNs, keys differing only
+	// in skipped fields, ...) have no deterministic order.
+	for i := 1; i < len(entries); i++ {
+		if bytes.Equal(entries[i-1].key, entries[i].key) {
+			return duplicateKey(p.typ)
+		}
+	}
+
+	count := uint32(len(entries))
+	writeFixed(buf, &count)
+
+	for _, e := range entries {
+		buf.Write(e.key)
+		buf.Write(e.val)
+	}
 	return nil
 }
 
-// Encode POD structs. Ensure that your objects
-// are pure POD and they are memory aligned.
+// Encode maps with basic keys and values.
+func encodeFixedMap[K comparable, V any](buf *Buffer, m map[K]V) {
+	// Keys of fast path maps can't have the same encoding.
+	if buf.opts.deterministic {
+		encodeSortedMap(buf, planOf(reflect.TypeFor[map[K]V]()), reflect.ValueOf(m), false)
+		return
+	}
+
+	count := uint32(len(m))
+	writeFixed(buf, &count)
+
+	for k, v := range m {
+		encodeFixed(buf, &k)
+		encodeFixed(buf, &v)
+	}
+}
+
+// Encode POD structs (and slices, arrays or maps of them) by copying their memory.
+// Structs with pointers, slices, strings, maps or interfaces return ErrNotPOD.
 func EncodePOD(buf *Buffer, object any) error {
+	return encodePOD(buf, object, true)
+}
+
+// Encode POD without checking its type, for types already known to be POD.
+// Memory of pointers and slices would be written as is.
+func EncodePODUnchecked(buf *Buffer, object any) error {
+	return encodePOD(buf, object, false)
+}
+
+func encodePOD(buf *Buffer, object any, check bool) error {
 	val := reflect.ValueOf(object)
 	val = reflect.Indirect(val)
 
@@ -71,38 +226,41 @@ func EncodePOD(buf *Buffer, object any) error {
 		return invalidValue(val)
 	}
 
+	p := buf.planOf(val.Type())
+
+	if check && p.pod != nil {
+		return p.pod
+	}
+
 	val = addressable(val)
 
 	switch val.Kind() {
 	case reflect.Struct:
-		size := int(val.Type().Size())
-		buf.Write(toBytes(val, size))
+		buf.writePOD(toBytes(val, p.size), p)
 
 	// Handle POD with slices, arrays, nested slices,
 	// named types, ...
 	default:
 		isPOD := true
-		return encode(buf, val, isPOD)
+		return encodeValue(buf, p, val, isPOD)
 	}
 	return nil
 }
 
 // Encode array.
-func encodeArray(buf *Buffer, val reflect.Value, isPOD bool) error {
-	elem := val.Type().Elem()
-
-	if isFixedType(elem.Kind()) {
-		size := int(elem.Size())
-		total := uint32(val.Len() * size)
+func encodeArray(buf *Buffer, p *plan, val reflect.Value, isPOD bool) error {
+	elem := p.elem
 
+	// Nested arrays and arrays of structs are copied at once.
+	if elem.flat || (isPOD && elem.podFlat) {
 		val = addressable(val)
-		buf.Write(toBytes(val, int(total)))
+		buf.writePOD(toBytes(val, p.size), elem)
 
 		return nil
 	}
 
 	for i := 0; i < val.Len(); i++ {
-		err := encode(buf, val.Index(i), isPOD)
+		err := encodeValue(buf, elem, val.Index(i), isPOD)
 		if err != nil {
 			return err
 		}
@@ -110,36 +268,58 @@ func encodeArray(buf *Buffer, val reflect.Value, isPOD bool) error {
 	return nil
 }
 
-func encodeStruct(buf *Buffer, val reflect.Value, isPOD bool) error {
-	val = reflect.Indirect(val)
-
+func encodeStruct(buf *Buffer, p *plan, val reflect.Value, isPOD bool) error {
 	val = addressable(val)
 
 	if isPOD {
-		size := int(val.Type().Size())
-		buf.Write(toBytes(val, size))
+		buf.writePOD(toBytes(val, p.size), p)
 		return nil
 	}
 
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
-		kind := field.Kind()
+	if p.err != nil {
+		return p.err
+	}
+
+	for i := 0; i < len(p.fields); i++ {
+		f := &p.fields[i]
+
+		if f.run > 0 {
+			src := unsafe.Slice((*byte)(unsafe.Add(unsafe.Pointer(val.UnsafeAddr()), f.offset)), f.runSize)
+			buf.writeRun(src, p.fields[i:i+f.run])
+
+			i += f.run - 1
+			continue
+		}
+
+		if f.flatPointer {
+			ptr := *(*unsafe.Pointer)(unsafe.Add(unsafe.Pointer(val.UnsafeAddr()), f.offset))
+			encodeFlatPointer(buf, ptr, f.plan.elem)
+			continue
+		}
 
-		if kind == reflect.Pointer {
-			ptrFlag := uint8(0)
+		field := val.Field(f.index)
 
-			if field.IsNil() {
-				buf.Write(ToBytes(&ptrFlag))
+		if !f.exported {
+			if !buf.opts.unexported {
 				continue
 			}
+			field = exposed(field)
+		}
 
-			ptrFlag = 1
-			buf.Write(ToBytes(&ptrFlag))
+		// Omitted fields have the same flag as pointers.
+		if f.omitEmpty {
+			flag := uint8(0)
+
+			if field.IsZero() {
+				writeFixed(buf, &flag)
+				continue
+			}
 
-			field = reflect.Indirect(field)
+			flag = 1
+			writeFixed(buf, &flag)
 		}
 
-		err := encode(buf, field, isPOD)
+		err := encodeValue(buf, f.plan, field, isPOD)
 		if err != nil {
 			return err
 		}
@@ -147,29 +327,103 @@ func encodeStruct(buf *Buffer, val reflect.Value, isPOD bool) error {
 	return nil
 }
 
+// Encode pointer as flag, followed by pointed value when it's not nil.
+func encodePointer(buf *Buffer, p *plan, val reflect.Value, isPOD bool) error {
+	ptrFlag := uint8(0)
+
+	if val.IsNil() {
+		writeFixed(buf, &ptrFlag)
+		return nil
+	}
+
+	ptrFlag = 1
+	writeFixed(buf, &ptrFlag)
+
+	return encodeValue(buf, p.elem, val.Elem(), isPOD)
+}
+
+// Encode pointer to flat value, copying pointed memory directly.
+func encodeFlatPointer(buf *Buffer, ptr unsafe.Pointer, elem *plan) {
+	ptrFlag := uint8(0)
+
+	if ptr == nil {
+		writeFixed(buf, &ptrFlag)
+		return
+	}
+
+	ptrFlag = 1
+	writeFixed(buf, &ptrFlag)
+
+	buf.writeTyped(unsafe.Slice((*byte)(ptr), elem.size), elem.typ)
+}
+
 // This also handle named types.
 func encode(buf *Buffer, val reflect.Value, isPOD bool) error {
+	return encodeValue(buf, buf.planOf(val.Type()), val, isPOD)
+}
+
+// Encode value using plan of its type.
+func encodeValue(buf *Buffer, p *plan, val reflect.Value, isPOD bool) error {
 	var err error
-	kind := val.Kind()
 
-	switch kind {
+	// Time and big numbers have dedicated encoding,
+	// even if they implement binary or text marshalers.
+	switch p.builtin {
+	case builtinTime:
+		encodeTime(buf, (*time.Time)(valuePointer(val)))
+		return nil
+	case builtinBigInt:
+		encodeBigInt(buf, (*big.Int)(valuePointer(val)))
+		return nil
+	case builtinBigRat:
+		encodeBigRat(buf, (*big.Rat)(valuePointer(val)))
+		return nil
+	case builtinBigFloat:
+		encodeBigFloat(buf, (*big.Float)(valuePointer(val)))
+		return nil
+	}
+
+	if p.custom != 0 {
+		if m, ok := asMarshaler(p, val); ok {
+			return encodeMarshaler(buf, m)
+		}
+
+		if ok, err := encodeBinary(buf, p, val); ok {
+			return err
+		}
+	}
+
+	switch p.kind {
 	case
 		reflect.Bool, reflect.Uintptr, reflect.Int8, reflect.Int16,
 		reflect.Int32, reflect.Int64, reflect.Uint8, reflect.Uint16,
 		reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64,
 		reflect.Complex64, reflect.Complex128:
 
-		size := int(val.Type().Size())
-		buf.Write(toBytes(val, size))
+		buf.writeTyped(toBytes(addressable(val), p.size), p.typ)
+
+	// Int and uint are always written as 64 bit values.
+	case reflect.Int:
+		v := val.Int()
+		writeFixed(buf, &v)
+	case reflect.Uint:
+		v := val.Uint()
+		writeFixed(buf, &v)
 
 	case reflect.Slice:
-		err = encodeSlice(buf, val, isPOD)
+		err = encodeSlice(buf, p, val, isPOD)
+	case reflect.Map:
+		err = encodeMap(buf, p, val, isPOD)
 	case reflect.Array:
-		err = encodeArray(buf, val, isPOD)
+		err = encodeArray(buf, p, val, isPOD)
 	case reflect.String:
 		encodeFixed(buf, val.String())
 	case reflect.Struct:
-		err = encodeStruct(buf, val, isPOD)
+		err = encodeStruct(buf, p, val, isPOD)
+	case reflect.Pointer:
+		err = encodePointer(buf, p, val, isPOD)
+	case reflect.Interface:
+		err = encodeInterface(buf, val, isPOD)
 	default:
 		err = invalidValue(val)
 	}
@@ -180,74 +434,86 @@ func encode(buf *Buffer, val reflect.Value, isPOD bool) error {
 func encodeFixed(buf *Buffer, obj any) bool {
 	switch val := obj.(type) {
 	// Values
+	case int:
+		v := int64(val)
+		writeFixed(buf, &v)
+	case uint:
+		v := uint64(val)
+		writeFixed(buf, &v)
 	case int8:
-		buf.Write(ToBytes(&val))
+		writeFixed(buf, &val)
 	case int16:
-		buf.Write(ToBytes(&val))
+		writeFixed(buf, &val)
 	case int32:
-		buf.Write(ToBytes(&val))
+		writeFixed(buf, &val)
 	case int64:
-		buf.Write(ToBytes(&val))
+		writeFixed(buf, &val)
 	case uint8:
-		buf.Write(ToBytes(&val))
+		writeFixed(buf, &val)
 	case uint16:
-		buf.Write(ToBytes(&val))
+		writeFixed(buf, &val)
 	case uint32:
-		buf.Write(ToBytes(&val))
+		writeFixed(buf, &val)
 	case uint64:
-		buf.Write(ToBytes(&val))
+		writeFixed(buf, &val)
 	case float32:
-		buf.Write(ToBytes(&val))
+		writeFixed(buf, &val)
 	case float64:
-		buf.Write(ToBytes(&val))
+		writeFixed(buf, &val)
 	case complex64:
-		buf.Write(ToBytes(&val))
+		writeFixed(buf, &val)
 	case complex128:
-		buf.Write(ToBytes(&val))
+		writeFixed(buf, &val)
 	case uintptr:
-		buf.Write(ToBytes(&val))
+		writeFixed(buf, &val)
 	case bool:
-		buf.Write(ToBytes(&val))
+		writeFixed(buf, &val)
 
 	// Pointers
+	case *int:
+		v := int64(*val)
+		writeFixed(buf, &v)
+	case *uint:
+		v := uint64(*val)
+		writeFixed(buf, &v)
 	case *int8:
-		buf.Write(ToBytes(val))
+		writeFixed(buf, val)
 	case *int16:
-		buf.Write(ToBytes(val))
+		writeFixed(buf, val)
 	case *int32:
-		buf.Write(ToBytes(val))
+		writeFixed(buf, val)
 	case *int64:
-		buf.Write(ToBytes(val))
+		writeFixed(buf, val)
 	case *uint8:
-		buf.Write(ToBytes(val))
+		writeFixed(buf, val)
 	case *uint16:
-		buf.Write(ToBytes(val))
+		writeFixed(buf, val)
 	case *uint32:
-		buf.Write(ToBytes(val))
+		writeFixed(buf, val)
 	case *uint64:
-		buf.Write(ToBytes(val))
+		writeFixed(buf, val)
 	case *float32:
-		buf.Write(ToBytes(val))
+		writeFixed(buf, val)
 	case *float64:
-		buf.Write(ToBytes(val))
+		writeFixed(buf, val)
 	case *complex64:
-		buf.Write(ToBytes(val))
+		writeFixed(buf, val)
 	case *complex128:
-		buf.Write(ToBytes(val))
+		writeFixed(buf, val)
 	case *uintptr:
-		buf.Write(ToBytes(val))
+		writeFixed(buf, val)
 	case *bool:
-		buf.Write(ToBytes(val))
+		writeFixed(buf, val)
 
 	// Bytes
 	case []byte:
 		l := uint32(len(val))
-		buf.Write(ToBytes(&l))
+		writeFixed(buf, &l)
 		buf.Write(val)
 
 	case *[]byte:
 		l := uint32(len(*val))
-		buf.Write(ToBytes(&l))
+		writeFixed(buf, &l)
 		buf.Write(*val)
 
 	// Strings
@@ -255,16 +521,67 @@ func encodeFixed(buf *Buffer, obj any) bool {
 		l := uint32(len(val))
 		b := unsafe.Slice(unsafe.StringData(val), len(val))
 
-		buf.Write(ToBytes(&l))
+		writeFixed(buf, &l)
 		buf.Write(b)
 
 	case *string:
 		l := uint32(len(*val))
 		b := unsafe.Slice(unsafe.StringData(*val), len(*val))
 
-		buf.Write(ToBytes(&l))
+		writeFixed(buf, &l)
 		buf.Write(b)
 
+	// Time
+	case time.Time:
+		encodeTime(buf, &val)
+	case *time.Time:
+		encodeTime(buf, val)
+	case time.Duration:
+		writeFixed(buf, &val)
+	case *time.Duration:
+		writeFixed(buf, val)
+
+	// Big numbers
+	case big.Int:
+		encodeBigInt(buf, &val)
+	case *big.Int:
+		encodeBigInt(buf, val)
+	case big.Rat:
+		encodeBigRat(buf, &val)
+	case *big.Rat:
+		encodeBigRat(buf, val)
+	case big.Float:
+		encodeBigFloat(buf, &val)
+	case *big.Float:
+		encodeBigFloat(buf, val)
+
+	// Maps
+	case map[string]string:
+		encodeFixedMap(buf, val)
+	case map[string]uint64:
+		encodeFixedMap(buf, val)
+	case map[string][]byte:
+		encodeFixedMap(buf, val)
+	case map[uint64]uint64:
+		encodeFixedMap(buf, val)
+	case map[uint64][]byte:
+		encodeFixedMap(buf, val)
+	case map[uint64]string:
+		encodeFixedMap(buf, val)
+
+	case *map[string]string:
+		encodeFixedMap(buf, *val)
+	case *map[string]uint64:
+		encodeFixedMap(buf, *val)
+	case *map[string][]byte:
+		encodeFixedMap(buf, *val)
+	case *map[uint64]uint64:
+		encodeFixedMap(buf, *val)
+	case *map[uint64][]byte:
+		encodeFixedMap(buf, *val)
+	case *map[uint64]string:
+		encodeFixedMap(buf, *val)
+
 	default:
 		return false
 	}
@@ -0,0 +1,208 @@
+package bitbox
+
+import (
+	"encoding/binary"
+	"reflect"
+	"unsafe"
+)
+
+// Byte order used on the wire.
+type ByteOrder uint8
+
+const (
+	NativeEndian ByteOrder = iota
+	LittleEndian
+	BigEndian
+)
+
+// Detect host byte order.
+var hostLittleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// Check if bytes need to be swapped for given byte order.
+func needSwap(order ByteOrder) bool {
+	switch order {
+	case LittleEndian:
+		return !hostLittleEndian
+	case BigEndian:
+		return hostLittleEndian
+	default:
+		return false
+	}
+}
+
+// Write fixed value, swapping its bytes when byte order differs from host.
+func writeFixed[T any](buf *Buffer, v *T) {
+	if !buf.opts.swap {
+		buf.Write(ToBytes(v))
+		return
+	}
+
+	x := *v
+	swapLayout(ToBytes(&x), reflect.TypeFor[T]())
+	buf.Write(ToBytes(&x))
+}
+
+// Read fixed value, swapping its bytes when byte order differs from host.
+func readFixed[T any](buf *Buffer, v *T) error {
+	b := ToBytes(v)
+
+	n := buf.Read(b)
+	if n < len(b) {
+		return shortRead(reflect.TypeFor[T](), len(b), n)
+	}
+
+	if buf.opts.swap {
+		swapLayout(b, reflect.TypeFor[T]())
+	}
+	return nil
+}
+
+// Write memory of values with given type.
+// Without swapping this is a plain copy.
+func (b *Buffer) writeTyped(src []byte, typ reflect.Type) {
+	if !b.opts.swap {
+		b.Write(src)
+		return
+	}
+
+	// Write can flush previous data, so take written bytes from the end.
+	b.Write(src)
+	swapLayout(b.data[len(b.data)-len(src):], typ)
+}
+
+// Write memory of POD structs, their padding bytes are written as zeros.
+func (b *Buffer) writePOD(src []byte, p *plan) {
+	b.writeTyped(src, p.typ)
+
+	if len(p.padding) == 0 {
+		return
+	}
+
+	dst := b.data[len(b.data)-len(src):]
+
+	for off := 0; off < len(dst); off += p.size {
+		for _, pad := range p.padding {
+			clear(dst[off+pad.offset : off+pad.offset+pad.size])
+		}
+	}
+}
+
+// Read memory of values with given type.
+// Without swapping this is a plain copy.
+func (b *Buffer) readTyped(dst []byte, typ reflect.Type) error {
+	n := 0
+
+	if b.r != nil && len(dst) >= b.chunk {
+		n = b.readDirect(dst)
+	} else {
+		n = b.Read(dst)
+	}
+	if n < len(dst) {
+		return shortRead(typ, len(dst), n)
+	}
+
+	if b.opts.swap {
+		swapLayout(dst, typ)
+	}
+	return nil
+}
+
+// Write memory of struct fields run.
+func (b *Buffer) writeRun(src []byte, run []fieldPlan) {
+	b.Write(src)
+
+	if b.opts.swap {
+		swapRun(b.data[len(b.data)-len(src):], run)
+	}
+}
+
+// Read memory of struct fields run.
+func (b *Buffer) readRun(dst []byte, run []fieldPlan) error {
+	n := b.Read(dst)
+	if n < len(dst) {
+		return shortRead(run[0].plan.typ, len(dst), n)
+	}
+
+	if b.opts.swap {
+		swapRun(dst, run)
+	}
+	return nil
+}
+
+// Swap bytes of every field in run.
+func swapRun(b []byte, run []fieldPlan) {
+	start := run[0].offset
+
+	for _, f := range run {
+		off := int(f.offset - start)
+		swapLayout(b[off:off+f.plan.size], f.plan.typ)
+	}
+}
+
+// Swap bytes of consecutive values with given type, in place.
+// Structs and arrays are swapped field by field, using their memory layout.
+func swapLayout(b []byte, typ reflect.Type) {
+	size := int(typ.Size())
+	if size == 0 {
+		return
+	}
+
+	switch kind := typ.Kind(); {
+	case isFixedType(kind):
+		word := size
+
+		// Complex numbers are swapped as two separate floats.
+		if kind == reflect.Complex64 || kind == reflect.Complex128 {
+			word = size / 2
+		}
+
+		swapWords(b[:len(b)/size*size], word)
+
+	case kind == reflect.Array:
+		swapLayout(b[:len(b)/size*size], typ.Elem())
+
+	case kind == reflect.Struct:
+		for off := 0; off+size <= len(b); off += size {
+			for i := 0; i < typ.NumField(); i++ {
+				field := typ.Field(i)
+				start := off + int(field.Offset)
+
+				swapLayout(b[start:start+int(field.Type.Size())], field.Type)
+			}
+		}
+	}
+}
+
+// Reverse bytes in every word of given size.
+func swapWords(b []byte, word int) {
+	switch word {
+	case 0, 1:
+		return
+	case 2:
+		for off := 0; off+2 <= len(b); off += 2 {
+			binary.BigEndian.PutUint16(b[off:], binary.LittleEndian.Uint16(b[off:]))
+		}
+		return
+	case 4:
+		for off := 0; off+4 <= len(b); off += 4 {
+			binary.BigEndian.PutUint32(b[off:], binary.LittleEndian.Uint32(b[off:]))
+		}
+		return
+	case 8:
+		for off := 0; off+8 <= len(b); off += 8 {
+			binary.BigEndian.PutUint64(b[off:], binary.LittleEndian.Uint64(b[off:]))
+		}
+		return
+	}
+
+	for off := 0; off+word <= len(b); off += word {
+		w := b[off : off+word]
+
+		for i, j := 0, word-1; i < j; i, j = i+1, j-1 {
+			w[i], w[j] = w[j], w[i]
+		}
+	}
+}
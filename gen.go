@@ -0,0 +1,137 @@
+package bitbox
+
+import (
+	"reflect"
+	"strconv"
+	"unsafe"
+)
+
+// Helpers used by code generated with bitboxgen.
+// They write and read the same bytes as Encode/Decode.
+
+// Write fixed value (numbers, bools and arrays of them).
+func WriteFixed[T any](buf *Buffer, v *T) {
+	writeFixed(buf, v)
+}
+
+// Read fixed value (numbers, bools and arrays of them).
+func ReadFixed[T any](buf *Buffer, v *T) error {
+	return readFixed(buf, v)
+}
+
+// Write int as 64 bit value.
+func WriteInt[T ~int](buf *Buffer, v T) {
+	x := int64(v)
+	writeFixed(buf, &x)
+}
+
+// Read int written as 64 bit value.
+func ReadInt[T ~int](buf *Buffer, v *T) error {
+	x := int64(0)
+
+	err := readFixed(buf, &x)
+	if err != nil {
+		return err
+	}
+
+	if intOverflows(x, strconv.IntSize) {
+		return overflow(reflect.TypeFor[T](), x)
+	}
+
+	*v = T(x)
+	return nil
+}
+
+// Write uint as 64 bit value.
+func WriteUint[T ~uint](buf *Buffer, v T) {
+	x := uint64(v)
+	writeFixed(buf, &x)
+}
+
+// Read uint written as 64 bit value.
+func ReadUint[T ~uint](buf *Buffer, v *T) error {
+	x := uint64(0)
+
+	err := readFixed(buf, &x)
+	if err != nil {
+		return err
+	}
+
+	if uintOverflows(x, strconv.IntSize) {
+		return overflow(reflect.TypeFor[T](), x)
+	}
+
+	*v = T(x)
+	return nil
+}
+
+// Write flag of pointer or omitted field.
+func WriteFlag(buf *Buffer, set bool) {
+	flag := uint8(0)
+	if set {
+		flag = 1
+	}
+	writeFixed(buf, &flag)
+}
+
+// Read flag of pointer or omitted field.
+func ReadFlag(buf *Buffer) (bool, error) {
+	flag := uint8(0)
+
+	err := readFixed(buf, &flag)
+	return flag != 0, err
+}
+
+// Write number of elements.
+func WriteLen(buf *Buffer, n int) {
+	l := uint32(n)
+	writeFixed(buf, &l)
+}
+
+// Read number of elements of slice type S, checked against limits
+// and remaining input, same as in Decode.
+func ReadLen[S any](buf *Buffer) (int, error) {
+	p := planOf(reflect.TypeFor[S]())
+	return readCount(buf, p.typ, p.elem.minSize, p.elem.size)
+}
+
+// Write string with length prefix.
+func WriteString(buf *Buffer, s string) {
+	WriteLen(buf, len(s))
+	buf.Write(unsafe.Slice(unsafe.StringData(s), len(s)))
+}
+
+// Read string with length prefix.
+func ReadString(buf *Buffer, s *string) error {
+	_, err := decodeFixed(buf, s)
+	return err
+}
+
+// Write slice of fixed values with length prefix.
+func WriteSlice[T any](buf *Buffer, s []T) {
+	WriteLen(buf, len(s))
+
+	size := len(s) * int(unsafe.Sizeof(*new(T)))
+	buf.writeTyped(unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(s))), size), reflect.TypeFor[T]())
+}
+
+// Read slice of fixed values with length prefix.
+func ReadSlice[T any](buf *Buffer, s *[]T) error {
+	return decodeFixedSlice(buf, s)
+}
+
+// Encode value the same way as nested value (struct field, slice element, ...)
+// is encoded by Encode. Unlike Encode, pointers are written with flag.
+func EncodeNested[T any](buf *Buffer, v *T) error {
+	return encode(buf, reflect.ValueOf(v).Elem(), false)
+}
+
+// Decode value the same way as nested value is decoded by Decode.
+func DecodeNested[T any](buf *Buffer, v *T) error {
+	return decode(buf, reflect.ValueOf(v).Elem(), false)
+}
+
+// Call custom decoder as nested value, counted in MaxDepth limit.
+func DecodeUnmarshaler(buf *Buffer, u Unmarshaler) error {
+	return decodeUnmarshaler(buf, u)
+}
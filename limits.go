@@ -0,0 +1,151 @@
+package bitbox
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Limits for decoding data from untrusted sources.
+// Zero value means no limit.
+type Limits struct {
+	// Max number of elements in one slice, map, string or []byte.
+	MaxElements int
+
+	// Max number of bytes read by single Decode/DecodePOD call.
+	// Checked before every allocation, memory allocated for one
+	// slice or map can't be bigger either.
+	MaxBytes int
+
+	// Max nesting of slices, arrays, maps and structs.
+	MaxDepth int
+}
+
+func limitExceeded(name string, want int64, limit int) error {
+	return fmt.Errorf("%w: %s requested=%d limit=%d", ErrLimitExceeded, name, want, limit)
+}
+
+// Start new top level decoding.
+// Nested calls (from Unmarshaler) keep current state.
+func (b *Buffer) begin() {
+	if b.depth > 0 {
+		return
+	}
+
+	b.start = b.pos
+}
+
+// Enter nested value.
+func (b *Buffer) enter() error {
+	b.depth++
+
+	if b.opts.limits.MaxDepth > 0 && b.depth > b.opts.limits.MaxDepth {
+		return limitExceeded("depth", int64(b.depth), b.opts.limits.MaxDepth)
+	}
+	return nil
+}
+
+// Leave nested value.
+func (b *Buffer) leave() {
+	b.depth--
+}
+
+// Read length prefix and check it before anything is allocated.
+// Each element needs at least minSize bytes of input, so count
+// can't be bigger than remaining data. Elements taking memory (size
+// bytes each) need at least one byte, even when they encode nothing.
+func readCount(buf *Buffer, typ reflect.Type, minSize int, size int) (int, error) {
+	l := uint32(0)
+
+	err := readFixed(buf, &l)
+	if err != nil {
+		return 0, err
+	}
+
+	if size > 0 && minSize == 0 {
+		minSize = 1
+	}
+
+	n := int64(l)
+	need := n * int64(minSize)
+	alloc := n * int64(size)
+	lim := buf.opts.limits
+
+	if lim.MaxElements > 0 && n > int64(lim.MaxElements) {
+		return 0, limitExceeded("elements", n, lim.MaxElements)
+	}
+
+	if lim.MaxBytes > 0 && int64(buf.pos-buf.start)+need > int64(lim.MaxBytes) {
+		return 0, limitExceeded("bytes", int64(buf.pos-buf.start)+need, lim.MaxBytes)
+	}
+
+	if lim.MaxBytes > 0 && alloc > int64(lim.MaxBytes) {
+		return 0, limitExceeded("allocated bytes", alloc, lim.MaxBytes)
+	}
+
+	// Streaming buffers don't know how much data is left.
+	if buf.r == nil && need > int64(buf.Len()) {
+		return 0, shortRead(typ, int(min(need, math.MaxInt)), buf.Len())
+	}
+
+	if n > math.MaxInt {
+		return 0, overflow(typ, n)
+	}
+	return int(n), nil
+}
+
+// Calculate minimal number of encoded bytes for value of given type.
+func minSize(typ reflect.Type) int {
+	return planOf(typ).minSize
+}
+
+// Calculate minimal number of encoded bytes, using plans of nested types.
+func (p *plan) calcMinSize() int {
+	switch p.builtin {
+	case builtinTime:
+		// Seconds, nanoseconds and zone flag.
+		return 13
+	case builtinBigInt:
+		// Sign and length prefix.
+		return 5
+	case builtinBigRat:
+		// Numerator and denominator length prefix.
+		return 9
+	case builtinBigFloat:
+		// Precision, mode, form and sign.
+		return 7
+	}
+
+	// Custom encoding must write at least one byte.
+	if p.custom != 0 {
+		return 1
+	}
+
+	switch p.kind {
+	case reflect.Int, reflect.Uint:
+		return 8
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Interface:
+		return 4
+	case reflect.Pointer:
+		return 1
+	case reflect.Array:
+		return p.typ.Len() * p.elem.minSize
+	case reflect.Struct:
+		size := 0
+		for _, f := range p.fields {
+			// Unexported fields may be skipped.
+			if !f.exported {
+				continue
+			}
+
+			if f.omitEmpty {
+				size++
+				continue
+			}
+			size += f.plan.minSize
+		}
+		return size
+	default:
+		return p.size
+	}
+}
@@ -0,0 +1,176 @@
+package bitbox
+
+import (
+	"encoding"
+	"reflect"
+)
+
+// Marshaler is implemented by types that encode themselves.
+// EncodeBitbox must write at least one byte and exactly
+// what DecodeBitbox reads back. Writing nothing is an error,
+// since such values can't be counted when decoding slices.
+type Marshaler interface {
+	EncodeBitbox(buf *Buffer) error
+}
+
+// Unmarshaler is implemented by types that decode themselves.
+type Unmarshaler interface {
+	DecodeBitbox(buf *Buffer) error
+}
+
+var (
+	marshalerType         = reflect.TypeFor[Marshaler]()
+	unmarshalerType       = reflect.TypeFor[Unmarshaler]()
+	binaryMarshalerType   = reflect.TypeFor[encoding.BinaryMarshaler]()
+	binaryUnmarshalerType = reflect.TypeFor[encoding.BinaryUnmarshaler]()
+	textMarshalerType     = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType   = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// Custom encoding methods of type.
+type custom uint8
+
+const (
+	customEncodePtr custom = 1 << iota
+	customDecodePtr
+
+	// Both marshal and unmarshal methods are available.
+	customBinary
+	customText
+)
+
+// Get custom encoding methods of type.
+func customOf(typ reflect.Type) custom {
+	c := custom(0)
+	ptr := reflect.PointerTo(typ)
+
+	if ptr.Implements(marshalerType) {
+		c |= customEncodePtr
+	}
+	if ptr.Implements(unmarshalerType) {
+		c |= customDecodePtr
+	}
+	if ptr.Implements(binaryMarshalerType) && ptr.Implements(binaryUnmarshalerType) {
+		c |= customBinary
+	}
+	if ptr.Implements(textMarshalerType) && ptr.Implements(textUnmarshalerType) {
+		c |= customText
+	}
+	return c
+}
+
+// Detect if type (or pointer to it) has custom encoding.
+func isMarshaler(typ reflect.Type) bool {
+	return customOf(typ) != 0
+}
+
+// Get Marshaler from value, using pointer receiver when needed.
+// Pointers are not called directly, they could be nil.
+func asMarshaler(p *plan, val reflect.Value) (Marshaler, bool) {
+	if p.kind == reflect.Pointer || p.custom&customEncodePtr == 0 {
+		return nil, false
+	}
+	return methodValue(val, marshalerType).(Marshaler), true
+}
+
+// Call custom encoder, making sure it wrote at least one byte.
+func encodeMarshaler(buf *Buffer, m Marshaler) error {
+	start := buf.written
+
+	err := m.EncodeBitbox(buf)
+	if err != nil {
+		return err
+	}
+
+	if buf.written == start {
+		return emptyEncoding(reflect.TypeOf(m))
+	}
+	return nil
+}
+
+// Get value with methods of given interface, using pointer receiver when needed.
+func methodValue(val reflect.Value, iface reflect.Type) any {
+	if val.Type().Implements(iface) {
+		return val.Interface()
+	}
+	return addressable(val).Addr().Interface()
+}
+
+// Encode types implementing encoding.BinaryMarshaler (or TextMarshaler when enabled).
+// Marshaled bytes are written with length prefix, same as []byte.
+func encodeBinary(buf *Buffer, p *plan, val reflect.Value) (bool, error) {
+	if p.kind == reflect.Pointer {
+		return false, nil
+	}
+
+	var data []byte
+	var err error
+
+	c := p.custom
+
+	switch {
+	case c&customBinary != 0:
+		data, err = methodValue(val, binaryMarshalerType).(encoding.BinaryMarshaler).MarshalBinary()
+	case c&customText != 0 && buf.opts.text:
+		data, err = methodValue(val, textMarshalerType).(encoding.TextMarshaler).MarshalText()
+	default:
+		return false, nil
+	}
+
+	if err != nil {
+		return true, err
+	}
+
+	l := uint32(len(data))
+	writeFixed(buf, &l)
+	buf.Write(data)
+
+	return true, nil
+}
+
+// Decode types implementing encoding.BinaryUnmarshaler (or TextUnmarshaler when enabled).
+func decodeBinary(buf *Buffer, p *plan, val reflect.Value) (bool, error) {
+	if !val.CanAddr() {
+		return false, nil
+	}
+
+	c := p.custom
+
+	if c&customBinary == 0 && (c&customText == 0 || !buf.opts.text) {
+		return false, nil
+	}
+
+	l, err := readCount(buf, p.typ, 1, 1)
+	if err != nil {
+		return true, err
+	}
+
+	data, err := buf.Next(l)
+	if err != nil {
+		return true, truncated(p.typ, err)
+	}
+
+	if c&customBinary != 0 {
+		return true, val.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+	}
+	return true, val.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText(data)
+}
+
+// Get Unmarshaler from addressable value.
+func asUnmarshaler(p *plan, val reflect.Value) (Unmarshaler, bool) {
+	if !val.CanAddr() || p.custom&customDecodePtr == 0 {
+		return nil, false
+	}
+	return val.Addr().Interface().(Unmarshaler), true
+}
+
+// Call custom decoder as nested value.
+func decodeUnmarshaler(buf *Buffer, u Unmarshaler) error {
+	err := buf.enter()
+	defer buf.leave()
+
+	if err != nil {
+		return err
+	}
+	return u.DecodeBitbox(buf)
+}
@@ -0,0 +1,239 @@
+package bitbox
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/big"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+// Amount wraps big.Int, it's encoded as sign + magnitude bytes.
+type Amount struct {
+	Int *big.Int
+}
+
+func (a Amount) EncodeBitbox(buf *Buffer) error {
+	if a.Int == nil {
+		return buf.Encode(int8(0), []byte(nil))
+	}
+	return buf.Encode(int8(a.Int.Sign()), a.Int.Bytes())
+}
+
+func (a *Amount) DecodeBitbox(buf *Buffer) error {
+	sign := int8(0)
+	mag := []byte{}
+
+	err := buf.Decode(&sign, &mag)
+	if err != nil {
+		return err
+	}
+
+	a.Int = new(big.Int).SetBytes(mag)
+	if sign < 0 {
+		a.Int.Neg(a.Int)
+	}
+	return nil
+}
+
+// Celsius is stored as tenths of degree.
+type Celsius float64
+
+func (c Celsius) EncodeBitbox(buf *Buffer) error {
+	return buf.Encode(int32(c * 10))
+}
+
+func (c *Celsius) DecodeBitbox(buf *Buffer) error {
+	v := int32(0)
+
+	err := buf.Decode(&v)
+	*c = Celsius(v) / 10
+	return err
+}
+
+var errBroken = errors.New("broken")
+
+type brokenMarshaler struct{}
+
+func (brokenMarshaler) EncodeBitbox(buf *Buffer) error  { return errBroken }
+func (*brokenMarshaler) DecodeBitbox(buf *Buffer) error { return errBroken }
+
+// Writes nothing, its values couldn't be decoded from slices.
+type emptyMarshaler struct{}
+
+func (emptyMarshaler) EncodeBitbox(buf *Buffer) error  { return nil }
+func (*emptyMarshaler) DecodeBitbox(buf *Buffer) error { return nil }
+
+func newAmount(s string) Amount {
+	i, _ := new(big.Int).SetString(s, 10)
+	return Amount{Int: i}
+}
+
+func TestMarshaler(t *testing.T) {
+	type transfer struct {
+		From   string
+		Amount Amount
+		Fee    *Amount
+		Temps  []Celsius
+	}
+
+	fee := newAmount("-21000")
+
+	runTest(t, "top_level", newAmount("123456789012345678901234567890"))
+	runTest(t, "named_fixed", Celsius(36.6))
+	runTest(t, "slice", []Amount{newAmount("1"), newAmount("-2"), newAmount("0")})
+	runTest(t, "array", [2]Celsius{-1.5, 20})
+	runTest(t, "map", map[string]Amount{"a": newAmount("99999999999999999999")})
+	runTest(t, "struct", transfer{From: "alice", Amount: newAmount("42"), Fee: &fee, Temps: []Celsius{1.5, 2.5}})
+
+	t.Run("wire", func(t *testing.T) {
+		buf := NewBuffer(nil)
+		err := Encode(buf, []Celsius{1.5})
+		AssertEqual(t, nil, err)
+
+		// count + int32 instead of float64
+		Assert(t, 8, buf.Len())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		type wrapper struct {
+			B brokenMarshaler
+		}
+
+		buf := NewBuffer(nil)
+		Assert(t, true, errors.Is(Encode(buf, wrapper{}), errBroken))
+
+		buf = NewBuffer([]byte{1, 2, 3})
+		out := wrapper{}
+		Assert(t, true, errors.Is(Decode(buf, &out), errBroken))
+
+		for _, in := range []any{emptyMarshaler{}, []emptyMarshaler{{}, {}, {}}, &struct{ E emptyMarshaler }{}} {
+			err := Encode(NewBuffer(nil), in)
+			Assert(t, true, errors.Is(err, ErrInvalidValue))
+			Assert(t, true, strings.Contains(err.Error(), "bitbox.emptyMarshaler.EncodeBitbox wrote no data"))
+		}
+
+		// Bytes flushed by stream encoder are counted too.
+		enc := NewEncoderSize(io.Discard, 16)
+		err := enc.Encode([]Amount{newAmount("1"), newAmount("2"), newAmount("3"), newAmount("4")})
+		AssertEqual(t, nil, err)
+		Assert(t, true, errors.Is(enc.Encode(emptyMarshaler{}), ErrInvalidValue))
+	})
+}
+
+// UUID implements only encoding.BinaryMarshaler/BinaryUnmarshaler.
+type UUID [16]byte
+
+func (u UUID) MarshalBinary() ([]byte, error) {
+	return u[:], nil
+}
+
+func (u *UUID) UnmarshalBinary(data []byte) error {
+	if len(data) != len(u) {
+		return errBroken
+	}
+
+	copy(u[:], data)
+	return nil
+}
+
+// Level implements only encoding.TextMarshaler/TextUnmarshaler.
+type Level uint8
+
+var levels = []string{"debug", "info", "error"}
+
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(levels[l]), nil
+}
+
+func (l *Level) UnmarshalText(data []byte) error {
+	for i, name := range levels {
+		if name == string(data) {
+			*l = Level(i)
+			return nil
+		}
+	}
+	return errBroken
+}
+
+func TestBinaryMarshaler(t *testing.T) {
+	type peer struct {
+		ID    UUID
+		Addr  netip.Addr
+		Ports []netip.AddrPort
+		Prev  *netip.Addr
+	}
+
+	addr := netip.MustParseAddr("2001:db8::1")
+	prev := netip.MustParseAddr("10.0.0.1")
+
+	runTest(t, "netip_addr", addr)
+	runTest(t, "uuid", UUID{1, 2, 3, 4})
+	runTest(t, "slice", []UUID{{1}, {2}, {3}})
+	runTest(t, "map_key", map[netip.Addr]uint64{addr: 1, prev: 2})
+	runTest(t, "struct", peer{
+		ID:    UUID{9, 9},
+		Addr:  addr,
+		Ports: []netip.AddrPort{netip.AddrPortFrom(prev, 8080)},
+		Prev:  &prev,
+	})
+
+	t.Run("wire", func(t *testing.T) {
+		data, _ := addr.MarshalBinary()
+
+		buf := NewBuffer(nil)
+		err := Encode(buf, addr)
+		AssertEqual(t, nil, err)
+
+		l := uint32(0)
+		err = Decode(buf, &l)
+		AssertEqual(t, nil, err)
+
+		Assert(t, uint32(len(data)), l)
+		Assert(t, true, bytes.Equal(data, buf.Data()))
+	})
+
+	t.Run("unmarshal_error", func(t *testing.T) {
+		buf := NewBuffer(nil)
+		err := Encode(buf, []byte{1, 2, 3})
+		AssertEqual(t, nil, err)
+
+		out := UUID{}
+		Assert(t, true, errors.Is(Decode(buf, &out), errBroken))
+	})
+}
+
+func TestTextMarshaler(t *testing.T) {
+	in := []Level{2, 0, 1}
+
+	t.Run("disabled", func(t *testing.T) {
+		buf := NewBuffer(nil)
+		err := Encode(buf, in)
+		AssertEqual(t, nil, err)
+
+		// count + raw bytes
+		Assert(t, 7, buf.Len())
+
+		out := []Level{}
+		err = Decode(buf, &out)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, in, out)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		buf := NewBuffer(nil)
+		buf.SetTextMarshaler(true)
+		err := buf.Encode(in)
+		AssertEqual(t, nil, err)
+
+		// count + 3x (length + name)
+		Assert(t, 4+4*3+len("errordebuginfo"), buf.Len())
+
+		out := []Level{}
+		err = buf.Decode(&out)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, in, out)
+	})
+}
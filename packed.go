@@ -0,0 +1,288 @@
+package bitbox
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Packed layout of fixed type, the same as encoding/binary uses:
+// struct fields follow each other without padding.
+type packedLayout struct {
+	segments []segment
+	size     int
+
+	// Packed layout is the same as memory, values are copied at once.
+	dense bool
+	err   error
+}
+
+// Memory of fields copied at once. Fields following each other
+// in memory with the same word size are merged.
+type segment struct {
+	offset int
+	size   int
+	word   int
+
+	// Blank (_) fields are written as zeros and skipped when reading.
+	blank bool
+}
+
+var layouts sync.Map
+
+// Encode fixed value (number, array, struct or slice of them) in packed layout,
+// same as binary.Write. Struct fields have no padding and slices have no length prefix.
+// Int, uint and uintptr have no fixed size, they return ErrNotPOD.
+func EncodePacked(buf *Buffer, object any) error {
+	val := reflect.Indirect(reflect.ValueOf(object))
+
+	if !val.IsValid() {
+		return invalidValue(val)
+	}
+
+	typ := val.Type()
+	num := 1
+
+	if typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+		num = val.Len()
+	} else {
+		val = addressable(val)
+	}
+
+	l := layoutOf(typ)
+	if l.err != nil {
+		return l.err
+	}
+
+	buf.writePacked(toBytes(val, num*int(typ.Size())), typ, l)
+	return nil
+}
+
+// Decode value written by EncodePacked or binary.Write. Slices are
+// filled up to their length, same as in binary.Read.
+func DecodePacked(buf *Buffer, object any) error {
+	val := reflect.ValueOf(object)
+
+	if val.Kind() != reflect.Slice {
+		if !isPointer(val.Kind()) || val.IsNil() {
+			return invalidValue(val)
+		}
+		val = val.Elem()
+	}
+
+	typ := val.Type()
+	num := 1
+
+	if typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+		num = val.Len()
+	}
+
+	l := layoutOf(typ)
+	if l.err != nil {
+		return l.err
+	}
+
+	return buf.readPacked(toBytes(val, num*int(typ.Size())), typ, l)
+}
+
+// Get packed layout of type, layouts are built once and cached per type.
+func layoutOf(typ reflect.Type) *packedLayout {
+	if l, ok := layouts.Load(typ); ok {
+		return l.(*packedLayout)
+	}
+
+	l := &packedLayout{}
+
+	if hasFixedSize(typ) {
+		l.err = l.add(typ, 0)
+	} else {
+		l.err = noFixedSize(typ)
+	}
+
+	l.dense = l.size == int(typ.Size())
+
+	for _, s := range l.segments {
+		l.dense = l.dense && !s.blank
+	}
+
+	actual, _ := layouts.LoadOrStore(typ, l)
+	return actual.(*packedLayout)
+}
+
+// Check if type has the same size on all platforms.
+func hasFixedSize(typ reflect.Type) bool {
+	for typ.Kind() == reflect.Array {
+		typ = typ.Elem()
+	}
+
+	switch kind := typ.Kind(); {
+	case kind == reflect.Int, kind == reflect.Uint, kind == reflect.Uintptr:
+		return false
+	case kind == reflect.Struct:
+		return true
+	default:
+		return isFixedType(kind)
+	}
+}
+
+// Add segments of value with given type at memory offset.
+func (l *packedLayout) add(typ reflect.Type, offset int) error {
+	switch kind := typ.Kind(); {
+	case kind == reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+
+			if !hasFixedSize(f.Type) {
+				return notPOD(typ, i)
+			}
+
+			if f.Name == "_" {
+				l.addSegment(segment{offset: offset + int(f.Offset), size: int(f.Type.Size()), blank: true})
+				continue
+			}
+
+			err := l.add(f.Type, offset+int(f.Offset))
+			if err != nil {
+				return err
+			}
+		}
+
+	case kind == reflect.Array:
+		elem := typ.Elem()
+		size := int(elem.Size())
+
+		// Arrays of numbers are one segment.
+		if isFixedType(elem.Kind()) {
+			l.addSegment(segment{offset: offset, size: int(typ.Size()), word: wordSize(elem)})
+			return nil
+		}
+
+		for i := 0; i < typ.Len(); i++ {
+			err := l.add(elem, offset+i*size)
+			if err != nil {
+				return err
+			}
+		}
+
+	default:
+		l.addSegment(segment{offset: offset, size: int(typ.Size()), word: wordSize(typ)})
+	}
+	return nil
+}
+
+// Add segment, merging it with previous one when possible.
+func (l *packedLayout) addSegment(s segment) {
+	l.size += s.size
+
+	if s.size == 0 {
+		return
+	}
+
+	if n := len(l.segments); n > 0 {
+		last := &l.segments[n-1]
+
+		if !s.blank && !last.blank && last.word == s.word && last.offset+last.size == s.offset {
+			last.size += s.size
+			return
+		}
+	}
+
+	l.segments = append(l.segments, s)
+}
+
+// Get size of words swapped for byte order.
+// Complex numbers are swapped as two separate floats.
+func wordSize(typ reflect.Type) int {
+	kind := typ.Kind()
+
+	if kind == reflect.Complex64 || kind == reflect.Complex128 {
+		return int(typ.Size()) / 2
+	}
+	return int(typ.Size())
+}
+
+// Write memory of values in packed layout.
+func (b *Buffer) writePacked(src []byte, typ reflect.Type, l *packedLayout) {
+	size := int(typ.Size())
+
+	if l.dense {
+		b.Write(src)
+
+		if b.opts.swap {
+			l.swap(b.data[len(b.data)-len(src):], size)
+		}
+		return
+	}
+
+	for off := 0; off < len(src); off += size {
+		for _, s := range l.segments {
+			if s.blank {
+				b.Write(make([]byte, s.size))
+				continue
+			}
+
+			b.Write(src[off+s.offset : off+s.offset+s.size])
+
+			if b.opts.swap {
+				swapWords(b.data[len(b.data)-s.size:], s.word)
+			}
+		}
+	}
+}
+
+// Swap bytes of values in dense layout, in place.
+func (l *packedLayout) swap(b []byte, size int) {
+	for off := 0; off < len(b); off += size {
+		for _, s := range l.segments {
+			swapWords(b[off+s.offset:off+s.offset+s.size], s.word)
+		}
+	}
+}
+
+// Read values in packed layout into their memory.
+func (b *Buffer) readPacked(dst []byte, typ reflect.Type, l *packedLayout) error {
+	size := int(typ.Size())
+
+	if l.dense {
+		n := 0
+
+		if b.r != nil && len(dst) >= b.chunk {
+			n = b.readDirect(dst)
+		} else {
+			n = b.Read(dst)
+		}
+		if n < len(dst) {
+			return shortRead(typ, len(dst), n)
+		}
+
+		if b.opts.swap {
+			l.swap(dst, size)
+		}
+		return nil
+	}
+
+	for off := 0; off < len(dst); off += size {
+		for _, s := range l.segments {
+			if s.blank {
+				_, err := b.Next(s.size)
+				if err != nil {
+					return truncated(typ, err)
+				}
+				continue
+			}
+
+			d := dst[off+s.offset : off+s.offset+s.size]
+
+			n := b.Read(d)
+			if n < len(d) {
+				return shortRead(typ, len(d), n)
+			}
+
+			if b.opts.swap {
+				swapWords(d, s.word)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package bitbox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type packedHeader struct {
+	Magic   [4]byte
+	Version uint8
+	Flags   uint32
+	_       [3]byte
+	Points  [2]podPair
+	Scale   float64
+	Phase   complex64
+	Ready   bool
+	Count   int16
+}
+
+func makePackedHeader(i int) packedHeader {
+	return packedHeader{
+		Magic:   [4]byte{'b', 'b', 'o', 'x'},
+		Version: uint8(i),
+		Flags:   0x01020304,
+		Points:  [2]podPair{{X: 1, Y: 2}, {X: uint32(i), Y: 4}},
+		Scale:   -1.5,
+		Phase:   complex(1, -2),
+		Ready:   true,
+		Count:   int16(-i),
+	}
+}
+
+func TestPacked(t *testing.T) {
+	orders := []struct {
+		name  string
+		order ByteOrder
+		std   binary.ByteOrder
+	}{
+		{name: "little", order: LittleEndian, std: binary.LittleEndian},
+		{name: "big", order: BigEndian, std: binary.BigEndian},
+	}
+
+	in := makePackedHeader(1)
+	ins := []packedHeader{makePackedHeader(2), makePackedHeader(3)}
+	aligned := []alignedStruct{{A: 1, B: 2, C: 3, D: 4}, {A: 5, B: 6, C: 7, D: 8}}
+
+	for _, o := range orders {
+		t.Run(o.name, func(t *testing.T) {
+			expected := bytes.NewBuffer(nil)
+			binary.Write(expected, o.std, &in)
+			binary.Write(expected, o.std, ins)
+			binary.Write(expected, o.std, aligned)
+			binary.Write(expected, o.std, uint32(7))
+
+			buf := NewBuffer(nil)
+			buf.SetByteOrder(o.order)
+
+			err := buf.EncodePacked(&in)
+			AssertEqual(t, nil, err)
+			err = buf.EncodePacked(ins)
+			AssertEqual(t, nil, err)
+			err = buf.EncodePacked(&aligned)
+			AssertEqual(t, nil, err)
+			err = buf.EncodePacked(uint32(7))
+			AssertEqual(t, nil, err)
+
+			Assert(t, true, bytes.Equal(expected.Bytes(), buf.Data()))
+
+			out := packedHeader{}
+			outs := make([]packedHeader, 2)
+			outAligned := make([]alignedStruct, 2)
+			outNum := uint32(0)
+
+			err = buf.DecodePacked(&out)
+			AssertEqual(t, nil, err)
+			err = buf.DecodePacked(outs)
+			AssertEqual(t, nil, err)
+			err = buf.DecodePacked(&outAligned)
+			AssertEqual(t, nil, err)
+			err = buf.DecodePacked(&outNum)
+			AssertEqual(t, nil, err)
+
+			AssertEqual(t, in, out)
+			AssertEqual(t, ins, outs)
+			AssertEqual(t, aligned, outAligned)
+			Assert(t, 7, outNum)
+			Assert(t, 0, buf.Len())
+		})
+	}
+
+	t.Run("stream", func(t *testing.T) {
+		wire := bytes.NewBuffer(nil)
+
+		enc := NewEncoderSize(wire, 16)
+		err := enc.EncodePacked(ins)
+		AssertEqual(t, nil, err)
+		err = enc.Flush()
+		AssertEqual(t, nil, err)
+
+		outs := make([]packedHeader, 2)
+		dec := NewDecoderSize(wire, 16)
+		err = dec.DecodePacked(outs)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, ins, outs)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		buf := NewBuffer(nil)
+		err := buf.EncodePacked(&in)
+		AssertEqual(t, nil, err)
+
+		data := buf.Data()
+
+		for i := 0; i < len(data); i++ {
+			out := packedHeader{}
+			err := DecodePacked(NewBuffer(data[:i]), &out)
+			Assert(t, true, errors.Is(err, ErrOutOfBounds))
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tests := []struct {
+			name string
+			in   any
+			err  string
+		}{
+			{name: "int_field", in: &struct{ N int }{}, err: ".N has type int"},
+			{name: "pointer_field", in: &podInvalid{}, err: "bitbox.podInvalid.Next has type *bitbox.podInvalid"},
+			{name: "nested", in: &[2]podNested{}, err: "bitbox.podPointer.ptr has type *int"},
+			{name: "int", in: new(int), err: "int has no fixed size"},
+			{name: "string", in: new(string), err: "string has no fixed size"},
+			{name: "nested_slice", in: &[][]uint8{}, err: "[]uint8 has no fixed size"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := EncodePacked(NewBuffer(nil), tt.in)
+				Assert(t, true, errors.Is(err, ErrNotPOD))
+				Assert(t, true, strings.Contains(err.Error(), tt.err))
+
+				err = DecodePacked(NewBuffer(make([]byte, 64)), tt.in)
+				Assert(t, true, errors.Is(err, ErrNotPOD))
+				Assert(t, true, strings.Contains(err.Error(), tt.err))
+			})
+		}
+
+		Assert(t, true, errors.Is(EncodePacked(NewBuffer(nil), nil), ErrInvalidValue))
+		Assert(t, true, errors.Is(DecodePacked(NewBuffer(nil), packedHeader{}), ErrInvalidValue))
+	})
+}
@@ -0,0 +1,237 @@
+package bitbox
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Types with dedicated encoding.
+type builtin uint8
+
+const (
+	builtinNone builtin = iota
+	builtinTime
+	builtinBigInt
+	builtinBigRat
+	builtinBigFloat
+)
+
+// Plan of encoding/decoding values of one type. Everything that
+// depends only on type is computed once, so encode and decode
+// don't need to inspect reflect.Type for every value.
+type plan struct {
+	typ      reflect.Type
+	kind     reflect.Kind
+	size     int
+	builtin  builtin
+	custom   custom
+	copyable bool
+	minSize  int
+
+	// Memory of values is the same as their encoding (fixed values, arrays
+	// and structs of them without padding), so it's copied at once.
+	// In POD mode structs with padding are copied too.
+	flat    bool
+	podFlat bool
+
+	// Plans of elements (slices, arrays, pointers, map values) and map keys.
+	elem *plan
+	key  *plan
+
+	// Encoded struct fields, err is set when tags are invalid.
+	fields []fieldPlan
+	err    error
+
+	// Set when values can't be encoded as POD.
+	// Padding of structs is written as zeros in POD mode.
+	pod     error
+	padding []padding
+}
+
+type fieldPlan struct {
+	structField
+	offset uintptr
+	plan   *plan
+
+	// Contiguous fixed fields are copied at once. First field of run
+	// has number of fields in it and their total size.
+	run     int
+	runSize int
+
+	// Pointer to flat value, its memory is copied without reflection.
+	flatPointer bool
+}
+
+var plans sync.Map
+
+// Get plan of type, plans are built once and cached per type.
+func planOf(typ reflect.Type) *plan {
+	if p, ok := plans.Load(typ); ok {
+		return p.(*plan)
+	}
+
+	// Recursive types point to plans which are still being built,
+	// they are published together when all are complete.
+	building := map[reflect.Type]*plan{}
+	buildPlan(typ, building)
+
+	for t, p := range building {
+		plans.LoadOrStore(t, p)
+	}
+
+	p, _ := plans.Load(typ)
+	return p.(*plan)
+}
+
+// Get plan of type, remembering the last one. Buffers usually
+// encode values of the same type over and over.
+func (b *Buffer) planOf(typ reflect.Type) *plan {
+	if b.last != nil && b.last.typ == typ {
+		return b.last
+	}
+
+	b.last = planOf(typ)
+	return b.last
+}
+
+func buildPlan(typ reflect.Type, building map[reflect.Type]*plan) *plan {
+	if p, ok := plans.Load(typ); ok {
+		return p.(*plan)
+	}
+
+	if p, ok := building[typ]; ok {
+		return p
+	}
+
+	p := &plan{
+		typ:      typ,
+		kind:     typ.Kind(),
+		size:     int(typ.Size()),
+		custom:   customOf(typ),
+		copyable: isCopyable(typ),
+	}
+	building[typ] = p
+
+	switch typ {
+	case timeType:
+		p.builtin = builtinTime
+	case bigIntType:
+		p.builtin = builtinBigInt
+	case bigRatType:
+		p.builtin = builtinBigRat
+	case bigFloatType:
+		p.builtin = builtinBigFloat
+	}
+
+	switch p.kind {
+	case reflect.Slice, reflect.Array, reflect.Pointer:
+		p.elem = buildPlan(typ.Elem(), building)
+	case reflect.Map:
+		p.key = buildPlan(typ.Key(), building)
+		p.elem = buildPlan(typ.Elem(), building)
+	case reflect.Struct:
+		fields, err := parseFields(typ)
+
+		p.err = err
+		p.fields = make([]fieldPlan, len(fields))
+
+		for i, f := range fields {
+			p.fields[i] = fieldPlan{
+				structField: f,
+				offset:      typ.Field(f.index).Offset,
+				plan:        buildPlan(typ.Field(f.index).Type, building),
+			}
+		}
+
+		findRuns(p.fields)
+
+		for i := range p.fields {
+			p.fields[i].flatPointer = isFlatPointer(&p.fields[i])
+		}
+	}
+
+	switch p.kind {
+	case reflect.Array:
+		p.flat = p.custom == 0 && p.elem.flat
+		p.podFlat = p.custom == 0 && p.elem.podFlat
+		p.padding = findPadding(typ)
+	case reflect.Struct:
+		p.flat = p.isFlatStruct()
+		p.podFlat = p.custom == 0 && p.builtin == builtinNone
+		p.padding = findPadding(typ)
+	default:
+		p.flat = p.copyable
+		p.podFlat = p.copyable
+	}
+
+	// Values can't contain themselves directly, only through pointers,
+	// slices or maps, so nested plans used here are already complete.
+	p.minSize = p.calcMinSize()
+	p.pod = checkPOD(typ, map[reflect.Type]bool{})
+	return p
+}
+
+// Check if struct is encoded as its memory: all fields are flat,
+// encoded in memory order and there is no padding between them.
+func (p *plan) isFlatStruct() bool {
+	if p.custom != 0 || p.builtin != builtinNone || p.err != nil {
+		return false
+	}
+
+	if len(p.fields) == 0 || len(p.fields) != p.typ.NumField() {
+		return false
+	}
+
+	end := uintptr(0)
+
+	for i := range p.fields {
+		f := &p.fields[i]
+
+		if !isRunField(f) || f.offset != end {
+			return false
+		}
+		end += uintptr(f.plan.size)
+	}
+	return int(end) == p.size
+}
+
+// Check if field can be copied from memory as part of run.
+func isRunField(f *fieldPlan) bool {
+	return f.exported && !f.omitEmpty && f.plan.flat
+}
+
+// Check if field is pointer to flat value. Pointers in recursive types
+// can be still without plan of pointed value, which is never flat.
+func isFlatPointer(f *fieldPlan) bool {
+	if !f.exported || f.omitEmpty || f.plan.kind != reflect.Pointer {
+		return false
+	}
+	return f.plan.elem != nil && f.plan.elem.flat
+}
+
+// Find runs of fixed fields encoded one after another,
+// with no padding between them in memory.
+func findRuns(fields []fieldPlan) {
+	for i := 0; i < len(fields); {
+		j := i + 1
+
+		if isRunField(&fields[i]) {
+			for j < len(fields) && isRunField(&fields[j]) {
+				prev := &fields[j-1]
+
+				if fields[j].offset != prev.offset+uintptr(prev.plan.size) {
+					break
+				}
+				j++
+			}
+		}
+
+		if j-i > 1 {
+			last := &fields[j-1]
+
+			fields[i].run = j - i
+			fields[i].runSize = int(last.offset-fields[i].offset) + last.plan.size
+		}
+		i = j
+	}
+}
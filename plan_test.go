@@ -0,0 +1,288 @@
+package bitbox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type treeNode struct {
+	Value    uint64
+	Next     *treeNode
+	Children []treeNode
+	Index    map[string]*treeNode
+}
+
+func TestPlanCache(t *testing.T) {
+	typ := reflect.TypeFor[Tx]()
+
+	p := planOf(typ)
+	Assert(t, p, planOf(typ))
+
+	// Nested plans are shared with top level plans.
+	Assert(t, planOf(reflect.TypeFor[NamedTypeSlice1]()), p.fields[7].plan)
+	Assert(t, planOf(reflect.TypeFor[uint64]()), p.fields[1].plan)
+}
+
+func TestRecursivePlan(t *testing.T) {
+	p := planOf(reflect.TypeFor[treeNode]())
+
+	// Next and Children point back to the same plan.
+	Assert(t, p, p.fields[1].plan.elem)
+	Assert(t, p, p.fields[2].plan.elem)
+	Assert(t, 8+1+4+4, p.minSize)
+
+	leaf := treeNode{Value: 3}
+	in := treeNode{
+		Value:    1,
+		Next:     &treeNode{Value: 2},
+		Children: []treeNode{leaf, {Value: 4, Next: &leaf}},
+		Index:    map[string]*treeNode{"leaf": &leaf, "nil": nil},
+	}
+
+	runTest(t, "tree", in)
+}
+
+func TestConcurrentPlans(t *testing.T) {
+	type item struct {
+		A []map[string][3]uint16
+		B *item
+	}
+
+	in := item{A: []map[string][3]uint16{{"a": {1, 2, 3}}}, B: &item{}}
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			out := item{}
+			buf := NewBuffer(nil)
+
+			err := Encode(buf, &in)
+			AssertEqual(t, nil, err)
+			err = Decode(buf, &out)
+			AssertEqual(t, nil, err)
+
+			AssertEqual(t, in, out)
+		}()
+	}
+	wg.Wait()
+}
+
+type runStruct struct {
+	A uint64
+	B uint32
+	C uint16
+	D [2]uint8
+	E string
+	F int64
+	G float64
+	H bool
+	I uint64 `bitbox:",omitempty"`
+	J uint32
+	K uint32
+}
+
+func TestFieldRuns(t *testing.T) {
+	p := planOf(reflect.TypeFor[runStruct]())
+
+	// A-D, F-H and J-K. I is omitempty and there is padding before it.
+	AssertEqual(t, []int{4, 0, 0, 0, 0, 3, 0, 0, 0, 2, 0}, fieldRuns(p))
+	AssertEqual(t, 16, p.fields[0].runSize)
+
+	in := runStruct{A: 1, B: 2, C: 3, D: [2]uint8{4, 5}, E: "e", F: -6, G: 7.5, H: true, I: 8, J: 9, K: 10}
+
+	for _, order := range []ByteOrder{LittleEndian, BigEndian} {
+		var std binary.AppendByteOrder = binary.LittleEndian
+		if order == BigEndian {
+			std = binary.BigEndian
+		}
+
+		want := std.AppendUint64(nil, 1)
+		want = std.AppendUint32(want, 2)
+		want = std.AppendUint16(want, 3)
+		want = append(want, 4, 5)
+		want = std.AppendUint32(want, 1)
+		want = append(want, 'e')
+		want = std.AppendUint64(want, uint64(in.F))
+		want = std.AppendUint64(want, math.Float64bits(7.5))
+		want = append(want, 1, 1)
+		want = std.AppendUint64(want, 8)
+		want = std.AppendUint32(want, 9)
+		want = std.AppendUint32(want, 10)
+
+		out := runStruct{}
+
+		buf := NewBuffer(nil)
+		buf.SetByteOrder(order)
+
+		err := Encode(buf, &in)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, want, buf.Data())
+
+		err = Decode(buf, &out)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, in, out)
+	}
+
+	t.Run("truncated", func(t *testing.T) {
+		out := runStruct{}
+
+		err := Decode(NewBuffer(make([]byte, 10)), &out)
+		Assert(t, true, errors.Is(err, ErrOutOfBounds))
+		Assert(t, true, strings.Contains(err.Error(), "runStruct.A"))
+	})
+}
+
+// Get run length of every field in plan.
+func fieldRuns(p *plan) []int {
+	runs := make([]int, len(p.fields))
+
+	for i, f := range p.fields {
+		runs[i] = f.run
+	}
+	return runs
+}
+
+type flatPoint struct {
+	X, Y int32
+}
+
+type flatSegment struct {
+	From, To flatPoint
+	Color    [4]uint8
+}
+
+func TestFlatPlans(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     reflect.Type
+		flat    bool
+		podFlat bool
+	}{
+		{name: "number", typ: reflect.TypeFor[uint32](), flat: true, podFlat: true},
+		{name: "nested_array", typ: reflect.TypeFor[[100][32]byte](), flat: true, podFlat: true},
+		{name: "struct", typ: reflect.TypeFor[flatSegment](), flat: true, podFlat: true},
+		{name: "array_struct", typ: reflect.TypeFor[[4][64]flatSegment](), flat: true, podFlat: true},
+		{name: "padding", typ: reflect.TypeFor[[2]podPadded](), flat: false, podFlat: true},
+		{name: "reordered", typ: reflect.TypeFor[struct {
+			A uint32
+			B uint32 `bitbox:"0"`
+		}](), flat: false, podFlat: true},
+		{name: "omitempty", typ: reflect.TypeFor[struct {
+			A uint32 `bitbox:",omitempty"`
+		}](), flat: false, podFlat: true},
+		{name: "unexported", typ: reflect.TypeFor[struct{ a uint32 }](), flat: false, podFlat: true},
+		{name: "string", typ: reflect.TypeFor[[2]string](), flat: false, podFlat: false},
+		{name: "time", typ: reflect.TypeFor[[2]time.Time](), flat: false, podFlat: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := planOf(tt.typ)
+			Assert(t, tt.flat, p.flat)
+			Assert(t, tt.podFlat, p.podFlat)
+		})
+	}
+}
+
+func TestFlatArrays(t *testing.T) {
+	segments := [3]flatSegment{}
+	for i := range segments {
+		segments[i] = flatSegment{From: flatPoint{int32(i), -1}, To: flatPoint{2, int32(i)}, Color: [4]uint8{uint8(i), 2, 3, 4}}
+	}
+
+	grid := [4][3]flatPoint{}
+	for i := range grid {
+		for j := range grid[i] {
+			grid[i][j] = flatPoint{int32(i), int32(j)}
+		}
+	}
+
+	runTest(t, "array_struct", segments)
+	runTest(t, "nested_array_struct", grid)
+	runTest(t, "slice_array_struct", [][3]flatSegment{segments, {}})
+	runTest(t, "struct_field", struct {
+		ID       uint8
+		Segments [3]flatSegment
+		Name     string
+	}{1, segments, "x"})
+
+	// Flat values have the same bytes as encoding/binary.
+	for _, o := range []struct {
+		order ByteOrder
+		std   binary.ByteOrder
+	}{{LittleEndian, binary.LittleEndian}, {BigEndian, binary.BigEndian}} {
+		expected := bytes.NewBuffer(nil)
+		binary.Write(expected, o.std, &segments)
+		binary.Write(expected, o.std, &grid)
+
+		buf := NewBuffer(nil)
+		buf.SetByteOrder(o.order)
+
+		err := Encode(buf, &segments, &grid)
+		AssertEqual(t, nil, err)
+		Assert(t, true, bytes.Equal(expected.Bytes(), buf.Data()))
+
+		outSegments := [3]flatSegment{}
+		outGrid := [4][3]flatPoint{}
+
+		err = Decode(buf, &outSegments, &outGrid)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, segments, outSegments)
+		AssertEqual(t, grid, outGrid)
+	}
+
+	t.Run("pod", func(t *testing.T) {
+		in := [2][2]podPadded{}
+
+		// Garbage in padding must not be copied.
+		mem := ToBytes(&in)
+		for i := range mem {
+			mem[i] = 0xff
+		}
+
+		for i := range in {
+			for j := range in[i] {
+				in[i][j].A = uint8(i)
+				in[i][j].B = uint32(j)
+				in[i][j].C = [2]podPair{{X: 1, Y: 2}, {X: 3, Y: 4}}
+				in[i][j].D = 5
+			}
+		}
+
+		clean := [2][2]podPadded{}
+		for i := range clean {
+			for j := range clean[i] {
+				clean[i][j] = podPadded{A: in[i][j].A, B: in[i][j].B, C: in[i][j].C, D: in[i][j].D}
+			}
+		}
+
+		single := NewBuffer(nil)
+		for i := range clean {
+			for j := range clean[i] {
+				err := single.EncodePOD(&clean[i][j])
+				AssertEqual(t, nil, err)
+			}
+		}
+
+		buf := NewBuffer(nil)
+		err := buf.EncodePOD(&in)
+		AssertEqual(t, nil, err)
+		Assert(t, true, bytes.Equal(single.Data(), buf.Data()))
+
+		out := [2][2]podPadded{}
+		err = buf.DecodePOD(&out)
+		AssertEqual(t, nil, err)
+		Assert(t, in, out)
+	})
+}
@@ -0,0 +1,129 @@
+package bitbox
+
+import (
+	"reflect"
+)
+
+// Range of padding bytes in struct memory.
+type padding struct {
+	offset int
+	size   int
+}
+
+// Find padding of struct, including padding of nested structs and arrays.
+func findPadding(typ reflect.Type) []padding {
+	return appendPadding(nil, typ, 0)
+}
+
+func appendPadding(pads []padding, typ reflect.Type, base int) []padding {
+	switch typ.Kind() {
+	case reflect.Array:
+		elem := typ.Elem()
+		size := int(elem.Size())
+
+		if elem.Kind() != reflect.Struct && elem.Kind() != reflect.Array {
+			return pads
+		}
+
+		for i := 0; i < typ.Len(); i++ {
+			pads = appendPadding(pads, elem, base+i*size)
+		}
+
+	case reflect.Struct:
+		end := 0
+
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			off := int(f.Offset)
+
+			pads = addPadding(pads, base+end, off-end)
+			pads = appendPadding(pads, f.Type, base+off)
+
+			end = off + int(f.Type.Size())
+		}
+
+		pads = addPadding(pads, base+end, int(typ.Size())-end)
+	}
+	return pads
+}
+
+// Add padding range, merging it with previous one when they touch.
+func addPadding(pads []padding, offset int, size int) []padding {
+	if size <= 0 {
+		return pads
+	}
+
+	if n := len(pads); n > 0 && pads[n-1].offset+pads[n-1].size == offset {
+		pads[n-1].size += size
+		return pads
+	}
+	return append(pads, padding{offset: offset, size: size})
+}
+
+// Check if values of type can be encoded as POD. Structs are copied as memory,
+// so they can't contain pointers, slices, strings, maps or interfaces.
+// Interfaces outside of structs are checked when their value is known.
+func checkPOD(typ reflect.Type, seen map[reflect.Type]bool) error {
+	if seen[typ] {
+		return nil
+	}
+	seen[typ] = true
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		return checkLayout(typ)
+	case reflect.Slice, reflect.Array, reflect.Pointer:
+		return checkPODElem(typ.Elem(), seen)
+	case reflect.Map:
+		err := checkPODElem(typ.Key(), seen)
+		if err != nil {
+			return err
+		}
+		return checkPODElem(typ.Elem(), seen)
+	}
+	return nil
+}
+
+// Nested structs with their own encoding are not copied as memory.
+func checkPODElem(typ reflect.Type, seen map[reflect.Type]bool) error {
+	if typ.Kind() == reflect.Struct && hasOwnEncoding(typ) {
+		return nil
+	}
+	return checkPOD(typ, seen)
+}
+
+// Check if type is always encoded and decoded by its own methods
+// or dedicated encoding, regardless of buffer options.
+func hasOwnEncoding(typ reflect.Type) bool {
+	switch typ {
+	case timeType, bigIntType, bigRatType, bigFloatType:
+		return true
+	}
+
+	c := customOf(typ)
+	return c&customBinary != 0 || (c&customEncodePtr != 0 && c&customDecodePtr != 0)
+}
+
+// Check that struct memory has only fixed values, including unexported
+// and skipped fields.
+func checkLayout(typ reflect.Type) error {
+	for i := 0; i < typ.NumField(); i++ {
+		ft := typ.Field(i).Type
+
+		for ft.Kind() == reflect.Array {
+			ft = ft.Elem()
+		}
+
+		switch {
+		case isFixedType(ft.Kind()):
+		case ft.Kind() == reflect.Struct:
+			err := checkLayout(ft)
+			if err != nil {
+				return err
+			}
+		default:
+			return notPOD(typ, i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package bitbox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type podInner struct {
+	X, Y int32
+	Tag  [4]byte
+}
+
+type podOuter struct {
+	ID     uint64
+	Points [2]podInner
+	Inner  podInner
+}
+
+type podInvalid struct {
+	Inner podInner
+	Next  *podInvalid
+}
+
+type podNested struct {
+	A     uint32
+	Inner podPointer
+}
+
+type podPointer struct {
+	N   uint32
+	ptr *int
+}
+
+type podPair struct {
+	X uint32
+	Y uint8
+}
+
+type podPadded struct {
+	A uint8
+	B uint32
+	C [2]podPair
+	D uint16
+}
+
+type podEvent interface {
+	Kind() string
+}
+
+type podPoint struct{ X, Y float64 }
+type podNamed struct{ Name string }
+
+func (podPoint) Kind() string { return "point" }
+func (podNamed) Kind() string { return "named" }
+
+func init() {
+	Register(100, podPoint{})
+	Register(101, podNamed{})
+}
+
+func TestPODCheck(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		for _, in := range []any{
+			&podOuter{ID: 1, Inner: podInner{X: -1}},
+			&[]podOuter{{ID: 1}, {ID: 2}},
+			&[2][]podInner{{{X: 1}}, nil},
+			&map[uint32]podInner{1: {Y: 2}},
+			&[]time.Time{time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC)},
+			&[]podEvent{podPoint{1, 2}, nil},
+			&[]string{"strings are not copied as memory"},
+		} {
+			buf := NewBuffer(nil)
+
+			err := EncodePOD(buf, in)
+			AssertEqual(t, nil, err)
+
+			out := reflect.New(reflect.TypeOf(in).Elem()).Interface()
+			err = DecodePOD(buf, out)
+			AssertEqual(t, nil, err)
+			AssertEqual(t, in, out)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tests := []struct {
+			name string
+			in   any
+			err  string
+		}{
+			{name: "pointer", in: &podInvalid{}, err: "bitbox.podInvalid.Next has type *bitbox.podInvalid"},
+			{name: "string", in: &struct{ S string }{}, err: ".S has type string"},
+			{name: "slice", in: &[]Tx{}, err: "bitbox.Tx.ChainID has type *uint64"},
+			{name: "array", in: &[1]struct{ A [2]string }{}, err: ".A has type [2]string"},
+			{name: "nested", in: &podNested{}, err: "bitbox.podPointer.ptr has type *int"},
+			{name: "map", in: &map[string]podNested{}, err: "bitbox.podPointer.ptr"},
+			{name: "interface_field", in: &struct{ E podEvent }{}, err: ".E has type bitbox.podEvent"},
+			{name: "time", in: &time.Time{}, err: "time.Time.loc"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				buf := NewBuffer(nil)
+
+				err := EncodePOD(buf, tt.in)
+				Assert(t, true, errors.Is(err, ErrNotPOD))
+				Assert(t, true, strings.Contains(err.Error(), tt.err))
+				Assert(t, 0, buf.Len())
+
+				err = DecodePOD(NewBuffer(make([]byte, 64)), tt.in)
+				Assert(t, true, errors.Is(err, ErrNotPOD))
+				Assert(t, true, strings.Contains(err.Error(), tt.err))
+			})
+		}
+	})
+
+	t.Run("interface", func(t *testing.T) {
+		in := []podEvent{podNamed{"x"}}
+
+		err := EncodePOD(NewBuffer(nil), &in)
+		Assert(t, true, errors.Is(err, ErrNotPOD))
+
+		buf := NewBuffer(nil)
+		err = Encode(buf, &in)
+		AssertEqual(t, nil, err)
+
+		out := []podEvent{}
+		err = DecodePOD(buf, &out)
+		Assert(t, true, errors.Is(err, ErrNotPOD))
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		err := EncodePOD(NewBuffer(nil), &podInvalid{})
+		Assert(t, err, EncodePOD(NewBuffer(nil), &podInvalid{}))
+	})
+}
+
+func TestPODUnchecked(t *testing.T) {
+	in := []podOuter{{ID: 1, Points: [2]podInner{{X: 1, Y: 2, Tag: [4]byte{'a'}}}}, {ID: 2}}
+	out := []podOuter{}
+
+	checked := NewBuffer(nil)
+	err := checked.EncodePOD(&in)
+	AssertEqual(t, nil, err)
+
+	buf := NewBuffer(nil)
+	err = buf.EncodePODUnchecked(&in)
+	AssertEqual(t, nil, err)
+	Assert(t, true, bytes.Equal(checked.Data(), buf.Data()))
+
+	err = buf.DecodePODUnchecked(&out)
+	AssertEqual(t, nil, err)
+	AssertEqual(t, in, out)
+}
+
+func TestPODPadding(t *testing.T) {
+	orders := []struct {
+		name  string
+		order ByteOrder
+		std   binary.ByteOrder
+	}{
+		{name: "little", order: LittleEndian, std: binary.LittleEndian},
+		{name: "big", order: BigEndian, std: binary.BigEndian},
+	}
+
+	pads := []padding{{offset: 1, size: 3}, {offset: 13, size: 3}, {offset: 21, size: 3}, {offset: 26, size: 2}}
+	AssertEqual(t, pads, findPadding(reflect.TypeFor[podPadded]()))
+
+	// Fill whole memory (including padding) with garbage before setting fields.
+	in := podPadded{}
+	mem := ToBytes(&in)
+	for i := range mem {
+		mem[i] = 0xff
+	}
+
+	in.A = 1
+	in.B = 2
+	in.C[0].X, in.C[0].Y = 3, 4
+	in.C[1].X, in.C[1].Y = 5, 6
+	in.D = 7
+
+	for _, o := range orders {
+		t.Run(o.name, func(t *testing.T) {
+			expected := make([]byte, 28)
+			expected[0] = 1
+			o.std.PutUint32(expected[4:], 2)
+			o.std.PutUint32(expected[8:], 3)
+			expected[12] = 4
+			o.std.PutUint32(expected[16:], 5)
+			expected[20] = 6
+			o.std.PutUint16(expected[24:], 7)
+
+			buf := NewBuffer(nil)
+			buf.SetByteOrder(o.order)
+
+			err := buf.EncodePOD(&in)
+			AssertEqual(t, nil, err)
+			Assert(t, true, bytes.Equal(expected, buf.Data()))
+
+			out := podPadded{}
+			err = buf.DecodePOD(&out)
+			AssertEqual(t, nil, err)
+			Assert(t, in, out)
+
+			slice := []podPadded{in, in}
+			buf.Clear()
+			err = buf.EncodePOD(&slice)
+			AssertEqual(t, nil, err)
+
+			count := make([]byte, 4)
+			o.std.PutUint32(count, 2)
+			Assert(t, true, bytes.Equal(slices.Concat(count, expected, expected), buf.Data()))
+
+			nested := struct{ P podPadded }{in}
+			buf.Clear()
+			err = buf.EncodePOD(&nested)
+			AssertEqual(t, nil, err)
+			Assert(t, true, bytes.Equal(expected, buf.Data()))
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package bitbox
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Concrete types of interface values, by their IDs.
+var registry = struct {
+	sync.RWMutex
+	types map[uint32]reflect.Type
+	ids   map[reflect.Type]uint32
+}{
+	types: map[uint32]reflect.Type{},
+	ids:   map[reflect.Type]uint32{},
+}
+
+// Register concrete type of prototype under given ID, so it can be
+// stored in interface values (any, custom interfaces). Interface values
+// are encoded as ID followed by concrete value. ID 0 is reserved for nil.
+//
+// Like gob.Register, it should be called during initialization.
+// It panics when ID or type is already registered with different pair.
+func Register(id uint32, prototype any) {
+	if id == 0 {
+		panic("bitbox: Register with reserved id 0")
+	}
+
+	if prototype == nil {
+		panic("bitbox: Register with nil prototype")
+	}
+
+	typ := reflect.TypeOf(prototype)
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if t, ok := registry.types[id]; ok && t != typ {
+		panic(fmt.Sprintf("bitbox: Register id %d used by %s and %s", id, t, typ))
+	}
+
+	if i, ok := registry.ids[typ]; ok && i != id {
+		panic(fmt.Sprintf("bitbox: Register type %s used with ids %d and %d", typ, i, id))
+	}
+
+	registry.types[id] = typ
+	registry.ids[typ] = id
+}
+
+// Get ID of registered type.
+func typeID(typ reflect.Type) (uint32, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	id, ok := registry.ids[typ]
+	return id, ok
+}
+
+// Get registered type by its ID.
+func typeByID(id uint32) (reflect.Type, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	typ, ok := registry.types[id]
+	return typ, ok
+}
+
+// Encode interface value as type ID and concrete value.
+func encodeInterface(buf *Buffer, val reflect.Value, isPOD bool) error {
+	id := uint32(0)
+
+	if val.IsNil() {
+		writeFixed(buf, &id)
+		return nil
+	}
+
+	elem := val.Elem()
+
+	id, ok := typeID(elem.Type())
+	if !ok {
+		return unknownType(elem.Type())
+	}
+
+	// Static POD check can't see types stored in interfaces.
+	if isPOD {
+		err := planOf(elem.Type()).pod
+		if err != nil {
+			return err
+		}
+	}
+
+	writeFixed(buf, &id)
+	return encode(buf, elem, isPOD)
+}
+
+// Decode interface value, creating new value of registered type.
+func decodeInterface(buf *Buffer, val reflect.Value, isPOD bool) error {
+	id := uint32(0)
+
+	err := readFixed(buf, &id)
+	if err != nil {
+		return err
+	}
+
+	if id == 0 {
+		val.Set(reflect.Zero(val.Type()))
+		return nil
+	}
+
+	typ, ok := typeByID(id)
+	if !ok {
+		return unknownTypeID(id)
+	}
+
+	if !typ.AssignableTo(val.Type()) {
+		return notAssignable(typ, val.Type())
+	}
+
+	if isPOD {
+		err = planOf(typ).pod
+		if err != nil {
+			return err
+		}
+	}
+
+	elem := reflect.New(typ).Elem()
+
+	err = decode(buf, elem, isPOD)
+	if err != nil {
+		return err
+	}
+
+	val.Set(elem)
+	return nil
+}
@@ -0,0 +1,129 @@
+package bitbox
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+type Event interface {
+	Kind() string
+}
+
+type Transfer struct {
+	From   string
+	To     string
+	Amount uint64
+}
+
+type Deposit struct {
+	Account string
+	Amount  Amount
+}
+
+func (Transfer) Kind() string { return "transfer" }
+func (*Deposit) Kind() string { return "deposit" }
+
+type unregistered struct{}
+
+func (unregistered) Kind() string { return "unregistered" }
+
+func init() {
+	Register(1, Transfer{})
+	Register(2, &Deposit{})
+	Register(3, uint64(0))
+	Register(4, "")
+	Register(5, []any{})
+	Register(6, map[string]any{})
+}
+
+func TestInterfaces(t *testing.T) {
+	type block struct {
+		Height uint64
+		Events []Event
+		Meta   any
+		Last   Event
+	}
+
+	in := block{
+		Height: 7,
+		Events: []Event{
+			Transfer{From: "alice", To: "bob", Amount: 10},
+			&Deposit{Account: "carol", Amount: newAmount("123456789012345678901234567890")},
+			nil,
+		},
+		Meta: map[string]any{"version": uint64(2), "tags": []any{"a", uint64(1), nil}},
+	}
+
+	runTest(t, "struct", in)
+	runTest(t, "any", []any{uint64(1), "two", Transfer{Amount: 3}, nil})
+	runTest(t, "map", map[string]Event{"t": Transfer{Amount: 1}, "nil": nil})
+
+	t.Run("top_level", func(t *testing.T) {
+		var in Event = Transfer{From: "dave", Amount: 99}
+		var out Event
+
+		buf := NewBuffer(nil)
+		err := Encode(buf, &in)
+		AssertEqual(t, nil, err)
+		err = Decode(buf, &out)
+		AssertEqual(t, nil, err)
+
+		AssertEqual(t, in, out)
+	})
+
+	t.Run("wire", func(t *testing.T) {
+		in := []any{uint64(5), nil}
+
+		buf := NewBuffer(nil)
+		err := Encode(buf, in)
+		AssertEqual(t, nil, err)
+
+		want := binary.NativeEndian.AppendUint32(nil, 2)
+		want = binary.NativeEndian.AppendUint32(want, 3)
+		want = binary.NativeEndian.AppendUint64(want, 5)
+		want = binary.NativeEndian.AppendUint32(want, 0)
+
+		AssertEqual(t, want, buf.Data())
+	})
+
+	t.Run("unregistered", func(t *testing.T) {
+		err := Encode(NewBuffer(nil), []Event{unregistered{}})
+		Assert(t, true, errors.Is(err, ErrUnknownType))
+	})
+
+	t.Run("unknown_id", func(t *testing.T) {
+		data := binary.NativeEndian.AppendUint32(nil, 1)
+		data = binary.NativeEndian.AppendUint32(data, 999)
+
+		out := []Event{}
+		err := Decode(NewBuffer(data), &out)
+		Assert(t, true, errors.Is(err, ErrUnknownType))
+	})
+
+	t.Run("not_implemented", func(t *testing.T) {
+		buf := NewBuffer(nil)
+		err := Encode(buf, []any{uint64(1)})
+		AssertEqual(t, nil, err)
+
+		out := []Event{}
+		err = Decode(buf, &out)
+		Assert(t, true, errors.Is(err, ErrInvalidValue))
+	})
+}
+
+func TestRegister(t *testing.T) {
+	panics := func(f func()) (ok bool) {
+		defer func() { ok = recover() != nil }()
+		f()
+		return false
+	}
+
+	// Registering the same pair again is allowed.
+	Assert(t, false, panics(func() { Register(1, Transfer{}) }))
+
+	Assert(t, true, panics(func() { Register(0, Transfer{}) }))
+	Assert(t, true, panics(func() { Register(100, nil) }))
+	Assert(t, true, panics(func() { Register(1, Deposit{}) }))
+	Assert(t, true, panics(func() { Register(100, Transfer{}) }))
+}
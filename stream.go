@@ -0,0 +1,225 @@
+package bitbox
+
+import (
+	"fmt"
+	"io"
+)
+
+// Default size of internal chunk used by Encoder.
+const defaultChunkSize = 64 * 1024
+
+// Encoder writes encoded data into io.Writer.
+// Data is collected in internal chunk and flushed when it fills,
+// so call Flush after the last Encode.
+type Encoder struct {
+	buf *Buffer
+}
+
+// Create new Encoder with default chunk size.
+func NewEncoder(w io.Writer) *Encoder {
+	return NewEncoderSize(w, defaultChunkSize)
+}
+
+// Create new Encoder with given chunk size.
+func NewEncoderSize(w io.Writer, size int) *Encoder {
+	if size <= 0 {
+		size = defaultChunkSize
+	}
+
+	buf := &Buffer{data: make([]byte, 0, size), w: w, chunk: size}
+	return &Encoder{buf: buf}
+}
+
+// Sort map entries by their encoded keys. See Buffer.SetDeterministic.
+func (e *Encoder) SetDeterministic(on bool) {
+	e.buf.SetDeterministic(on)
+}
+
+// Set byte order used on the wire. See Buffer.SetByteOrder.
+func (e *Encoder) SetByteOrder(order ByteOrder) {
+	e.buf.SetByteOrder(order)
+}
+
+// Use encoding.TextMarshaler as fallback. See Buffer.SetTextMarshaler.
+func (e *Encoder) SetTextMarshaler(on bool) {
+	e.buf.SetTextMarshaler(on)
+}
+
+// Encode unexported struct fields. See Buffer.SetUnexported.
+func (e *Encoder) SetUnexported(on bool) {
+	e.buf.SetUnexported(on)
+}
+
+// Encode objects into underlying writer.
+func (e *Encoder) Encode(objects ...any) error {
+	if e.buf.err != nil {
+		return e.buf.err
+	}
+
+	err := Encode(e.buf, objects...)
+	if err != nil {
+		return err
+	}
+	return e.buf.err
+}
+
+// Encode POD object into underlying writer.
+func (e *Encoder) EncodePOD(object any) error {
+	if e.buf.err != nil {
+		return e.buf.err
+	}
+
+	err := EncodePOD(e.buf, object)
+	if err != nil {
+		return err
+	}
+	return e.buf.err
+}
+
+// Encode POD object without checking its type. See EncodePODUnchecked.
+func (e *Encoder) EncodePODUnchecked(object any) error {
+	if e.buf.err != nil {
+		return e.buf.err
+	}
+
+	err := EncodePODUnchecked(e.buf, object)
+	if err != nil {
+		return err
+	}
+	return e.buf.err
+}
+
+// Encode object in packed layout. See EncodePacked.
+func (e *Encoder) EncodePacked(object any) error {
+	if e.buf.err != nil {
+		return e.buf.err
+	}
+
+	err := EncodePacked(e.buf, object)
+	if err != nil {
+		return err
+	}
+	return e.buf.err
+}
+
+// Write all buffered data into underlying writer.
+func (e *Encoder) Flush() error {
+	return e.buf.flush()
+}
+
+// Decoder reads encoded data from io.Reader.
+// Internal chunk is refilled on demand, truncated data
+// is reported as io.ErrUnexpectedEOF.
+type Decoder struct {
+	buf *Buffer
+}
+
+// Create new Decoder with default chunk size.
+func NewDecoder(r io.Reader) *Decoder {
+	return NewDecoderSize(r, defaultChunkSize)
+}
+
+// Create new Decoder with given chunk size.
+func NewDecoderSize(r io.Reader, size int) *Decoder {
+	if size <= 0 {
+		size = defaultChunkSize
+	}
+
+	buf := &Buffer{data: make([]byte, 0, size), r: r, chunk: size}
+	return &Decoder{buf: buf}
+}
+
+// Set byte order used on the wire. See Buffer.SetByteOrder.
+func (d *Decoder) SetByteOrder(order ByteOrder) {
+	d.buf.SetByteOrder(order)
+}
+
+// Use encoding.TextUnmarshaler as fallback. See Buffer.SetTextMarshaler.
+func (d *Decoder) SetTextMarshaler(on bool) {
+	d.buf.SetTextMarshaler(on)
+}
+
+// Decode unexported struct fields. See Buffer.SetUnexported.
+func (d *Decoder) SetUnexported(on bool) {
+	d.buf.SetUnexported(on)
+}
+
+// Set limits for decoding untrusted data. Decoder can't check length
+// prefixes against remaining input, so set MaxBytes for untrusted peers.
+func (d *Decoder) SetLimits(limits Limits) {
+	d.buf.SetLimits(limits)
+}
+
+// Decode next values from underlying reader into objects.
+// Returns io.EOF when stream ends cleanly before the first object.
+func (d *Decoder) Decode(objects ...any) error {
+	err := d.begin()
+	if err != nil {
+		return err
+	}
+
+	err = Decode(d.buf, objects...)
+	return d.result(err)
+}
+
+// Decode next POD value from underlying reader.
+func (d *Decoder) DecodePOD(object any) error {
+	err := d.begin()
+	if err != nil {
+		return err
+	}
+
+	err = DecodePOD(d.buf, object)
+	return d.result(err)
+}
+
+// Decode next POD value without checking its type. See DecodePODUnchecked.
+func (d *Decoder) DecodePODUnchecked(object any) error {
+	err := d.begin()
+	if err != nil {
+		return err
+	}
+
+	err = DecodePODUnchecked(d.buf, object)
+	return d.result(err)
+}
+
+// Decode next value in packed layout. See DecodePacked.
+func (d *Decoder) DecodePacked(object any) error {
+	err := d.begin()
+	if err != nil {
+		return err
+	}
+
+	err = DecodePacked(d.buf, object)
+	return d.result(err)
+}
+
+// Check if there is any data left before decoding next message.
+func (d *Decoder) begin() error {
+	if d.buf.Len() > 0 {
+		return nil
+	}
+
+	d.buf.fill(1)
+
+	if d.buf.Len() == 0 {
+		if d.buf.err != nil {
+			return d.buf.err
+		}
+		return io.EOF
+	}
+	return nil
+}
+
+// Merge decoding error with reader error.
+func (d *Decoder) result(err error) error {
+	if err == nil {
+		return d.buf.err
+	}
+
+	if d.buf.err != nil {
+		return fmt.Errorf("%w: %w", d.buf.err, err)
+	}
+	return err
+}
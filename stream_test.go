@@ -0,0 +1,244 @@
+package bitbox
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"testing/iotest"
+)
+
+type failingWriter struct {
+	n int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n <= 0 {
+		return 0, io.ErrShortWrite
+	}
+
+	w.n--
+	return len(p), nil
+}
+
+func makeStreamTx(i int) Tx {
+	chainID := uint64(i)
+	to := NamedTypeArray2{byte(i)}
+
+	return Tx{
+		ChainID:    &chainID,
+		Nonce:      uint64(i),
+		To:         &to,
+		Data:       bytes.Repeat([]byte{byte(i)}, i%64+1),
+		AccessList: NamedTypeSlice1{uint16(i), 1, 2},
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	t.Run("same_bytes_as_buffer", func(t *testing.T) {
+		buf := NewBuffer(nil)
+		wire := bytes.NewBuffer(nil)
+		enc := NewEncoderSize(wire, 16)
+
+		for i := 0; i < 200; i++ {
+			tx := makeStreamTx(i)
+
+			err := buf.Encode(&tx, "tx", uint32(i))
+			AssertEqual(t, nil, err)
+			err = enc.Encode(&tx, "tx", uint32(i))
+			AssertEqual(t, nil, err)
+		}
+
+		err := enc.Flush()
+		AssertEqual(t, nil, err)
+
+		Assert(t, true, bytes.Equal(buf.Data(), wire.Bytes()))
+	})
+
+	t.Run("byte_order", func(t *testing.T) {
+		nums := make([]uint64, 1000)
+		for i := range nums {
+			nums[i] = uint64(i) << 40
+		}
+
+		buf := NewBuffer(nil)
+		buf.SetByteOrder(BigEndian)
+		err := buf.Encode(nums)
+		AssertEqual(t, nil, err)
+
+		wire := bytes.NewBuffer(nil)
+		enc := NewEncoderSize(wire, 100)
+		enc.SetByteOrder(BigEndian)
+		err = enc.Encode(nums)
+		AssertEqual(t, nil, err)
+		err = enc.Flush()
+		AssertEqual(t, nil, err)
+
+		Assert(t, true, bytes.Equal(buf.Data(), wire.Bytes()))
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		in := make([][]uint64, 100)
+		for i := range in {
+			in[i] = make([]uint64, 1000)
+		}
+
+		wire := bytes.NewBuffer(nil)
+		zw := gzip.NewWriter(wire)
+		enc := NewEncoder(zw)
+
+		err := enc.Encode(in)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, nil, enc.Flush())
+		AssertEqual(t, nil, zw.Close())
+
+		zr, err := gzip.NewReader(wire)
+		AssertEqual(t, nil, err)
+		data, err := io.ReadAll(zr)
+		AssertEqual(t, nil, err)
+
+		out := [][]uint64{}
+		err = NewBuffer(data).Decode(&out)
+		AssertEqual(t, nil, err)
+
+		AssertEqual(t, in, out)
+	})
+
+	t.Run("writer_error", func(t *testing.T) {
+		enc := NewEncoderSize(&failingWriter{n: 1}, 8)
+
+		var err error
+		for i := 0; i < 10 && err == nil; i++ {
+			err = enc.Encode(uint64(i))
+		}
+
+		Assert(t, true, errors.Is(err, io.ErrShortWrite))
+		Assert(t, true, errors.Is(enc.Flush(), io.ErrShortWrite))
+	})
+}
+
+func TestDecoder(t *testing.T) {
+	encodeTxs := func(t *testing.T, n int) ([]Tx, []byte) {
+		t.Helper()
+
+		txs := make([]Tx, n)
+		buf := NewBuffer(nil)
+
+		for i := range txs {
+			txs[i] = makeStreamTx(i)
+			err := buf.Encode(&txs[i])
+			AssertEqual(t, nil, err)
+		}
+		return txs, buf.Data()
+	}
+
+	t.Run("sequence", func(t *testing.T) {
+		in, data := encodeTxs(t, 200)
+		dec := NewDecoderSize(iotest.OneByteReader(bytes.NewReader(data)), 16)
+
+		out := []Tx{}
+		for {
+			tx := Tx{}
+
+			err := dec.Decode(&tx)
+			if err == io.EOF {
+				break
+			}
+			AssertEqual(t, nil, err)
+
+			out = append(out, tx)
+		}
+
+		AssertEqual(t, in, out)
+	})
+
+	t.Run("big_values", func(t *testing.T) {
+		in := make([]uint64, 10000)
+		for i := range in {
+			in[i] = uint64(i)
+		}
+		str := string(bytes.Repeat([]byte("bitbox"), 1000))
+
+		buf := NewBuffer(nil)
+		buf.SetByteOrder(BigEndian)
+		err := buf.Encode(in, str)
+		AssertEqual(t, nil, err)
+
+		out := []uint64{}
+		outStr := ""
+
+		dec := NewDecoderSize(iotest.HalfReader(bytes.NewReader(buf.Data())), 64)
+		dec.SetByteOrder(BigEndian)
+		err = dec.Decode(&out, &outStr)
+		AssertEqual(t, nil, err)
+
+		AssertEqual(t, in, out)
+		AssertEqual(t, str, outStr)
+		AssertEqual(t, io.EOF, dec.Decode(&outStr))
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		_, data := encodeTxs(t, 1)
+
+		for i := 1; i < len(data); i++ {
+			tx := Tx{}
+			dec := NewDecoderSize(bytes.NewReader(data[:i]), 8)
+
+			err := dec.Decode(&tx)
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Fatalf("truncated at %d: expected io.ErrUnexpectedEOF, got %v", i, err)
+			}
+		}
+	})
+
+	t.Run("reader_error", func(t *testing.T) {
+		dec := NewDecoder(iotest.ErrReader(io.ErrClosedPipe))
+
+		out := uint64(0)
+		err := dec.Decode(&out)
+		Assert(t, true, errors.Is(err, io.ErrClosedPipe))
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		hostile := []byte{0xff, 0xff, 0xff, 0xff, 0x01}
+
+		out := []uint64{}
+		dec := NewDecoderSize(bytes.NewReader(hostile), 16)
+		dec.SetLimits(Limits{MaxBytes: 1 << 20})
+
+		allocated := allocatedBytes(func() {
+			err := dec.Decode(&out)
+			Assert(t, true, errors.Is(err, ErrLimitExceeded))
+		})
+		Assert(t, true, allocated < 1024)
+	})
+
+	t.Run("connection", func(t *testing.T) {
+		in, _ := encodeTxs(t, 50)
+		client, server := net.Pipe()
+
+		go func() {
+			defer client.Close()
+			enc := NewEncoder(client)
+
+			for i := range in {
+				enc.Encode(&in[i])
+				enc.Flush()
+			}
+		}()
+
+		dec := NewDecoder(server)
+		for i := range in {
+			tx := Tx{}
+
+			err := dec.Decode(&tx)
+			AssertEqual(t, nil, err)
+			AssertEqual(t, in[i], tx)
+		}
+
+		tx := Tx{}
+		AssertEqual(t, io.EOF, dec.Decode(&tx))
+	})
+}
@@ -0,0 +1,90 @@
+package bitbox
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Encoded struct field, described by `bitbox:"..."` tag:
+//
+//	`bitbox:"-"`           field is skipped
+//	`bitbox:"2"`           field is encoded at given position
+//	`bitbox:",omitempty"`  zero value is encoded as single flag byte
+//	`bitbox:"2,omitempty"` both
+//
+// Fields with position are encoded first, in ascending order,
+// followed by remaining fields in declaration order. Unexported
+// fields are skipped, unless enabled with SetUnexported.
+type structField struct {
+	index     int
+	pos       int
+	omitEmpty bool
+	exported  bool
+}
+
+func invalidTag(typ reflect.Type, i int, msg string) error {
+	return fmt.Errorf("%w: %s.%s %s", ErrInvalidTag, typ.String(), typ.Field(i).Name, msg)
+}
+
+// Parse encoded fields of struct type from their tags.
+func parseFields(typ reflect.Type) ([]structField, error) {
+	fields := make([]structField, 0, typ.NumField())
+	ordinals := map[int]int{}
+
+	for i := 0; i < typ.NumField(); i++ {
+		exported := typ.Field(i).IsExported()
+
+		tag, ok := typ.Field(i).Tag.Lookup("bitbox")
+		if !ok {
+			fields = append(fields, structField{index: i, pos: -1, exported: exported})
+			continue
+		}
+
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		field := structField{index: i, pos: -1, exported: exported}
+
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "":
+			case "omitempty":
+				field.omitEmpty = true
+			default:
+				return nil, invalidTag(typ, i, "unknown option "+opt)
+			}
+		}
+
+		if name != "" {
+			ord, err := strconv.Atoi(name)
+			if err != nil || ord < 0 {
+				return nil, invalidTag(typ, i, "invalid position "+name)
+			}
+
+			if j, ok := ordinals[ord]; ok {
+				return nil, invalidTag(typ, i, "has the same position as "+typ.Field(j).Name)
+			}
+			ordinals[ord] = i
+			field.pos = ord
+		}
+
+		fields = append(fields, field)
+	}
+
+	// Fields with position go first. Sort is stable,
+	// so remaining fields keep declaration order.
+	sort.SliceStable(fields, func(a, b int) bool {
+		pa, pb := fields[a].pos, fields[b].pos
+
+		if pa >= 0 && pb >= 0 {
+			return pa < pb
+		}
+		return pa >= 0 && pb < 0
+	})
+	return fields, nil
+}
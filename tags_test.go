@@ -0,0 +1,174 @@
+package bitbox
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type taggedAccount struct {
+	mu      sync.Mutex        `bitbox:"-"`
+	Name    string            `bitbox:"2"`
+	ID      uint32            `bitbox:"1"`
+	Balance uint64            `bitbox:",omitempty"`
+	Tags    []string          `bitbox:"3,omitempty"`
+	Cache   map[string]uint64 `bitbox:"-"`
+	Flags   uint16
+}
+
+func TestStructTags(t *testing.T) {
+	in := &taggedAccount{Name: "alice", ID: 7, Tags: []string{"vip"}, Flags: 3}
+	in.Cache = map[string]uint64{"derived": 1}
+
+	buf := NewBuffer(nil)
+	err := Encode(buf, in)
+	AssertEqual(t, nil, err)
+
+	// ID, Name, Tags, Balance, Flags.
+	want := binary.NativeEndian.AppendUint32(nil, 7)
+	want = binary.NativeEndian.AppendUint32(want, 5)
+	want = append(want, "alice"...)
+	want = append(want, 1)
+	want = binary.NativeEndian.AppendUint32(want, 1)
+	want = binary.NativeEndian.AppendUint32(want, 3)
+	want = append(want, "vip"...)
+	want = append(want, 0)
+	want = binary.NativeEndian.AppendUint16(want, 3)
+
+	AssertEqual(t, want, buf.Data())
+
+	out := &taggedAccount{Balance: 99, Cache: map[string]uint64{"kept": 2}}
+	err = Decode(buf, out)
+	AssertEqual(t, nil, err)
+
+	AssertEqual(t, in.Name, out.Name)
+	AssertEqual(t, in.ID, out.ID)
+	AssertEqual(t, uint64(0), out.Balance)
+	AssertEqual(t, in.Tags, out.Tags)
+	AssertEqual(t, in.Flags, out.Flags)
+
+	// Skipped fields are not touched.
+	AssertEqual(t, map[string]uint64{"kept": 2}, out.Cache)
+}
+
+func TestOmitEmpty(t *testing.T) {
+	type item struct {
+		A uint64  `bitbox:",omitempty"`
+		B *uint64 `bitbox:",omitempty"`
+		C string  `bitbox:",omitempty"`
+		D [4]byte `bitbox:",omitempty"`
+	}
+
+	b := uint64(5)
+
+	runTest(t, "empty", item{})
+	runTest(t, "full", item{A: 1, B: &b, C: "c", D: [4]byte{1}})
+	runTest(t, "slice", []item{{}, {A: 2}, {C: "x"}})
+
+	buf := NewBuffer(nil)
+	err := Encode(buf, item{})
+	AssertEqual(t, nil, err)
+	AssertEqual(t, []byte{0, 0, 0, 0}, buf.Data())
+}
+
+func TestInvalidTags(t *testing.T) {
+	type duplicate struct {
+		A uint8 `bitbox:"1"`
+		B uint8 `bitbox:"1"`
+	}
+	type position struct {
+		A uint8 `bitbox:"first"`
+	}
+	type option struct {
+		A uint8 `bitbox:",compress"`
+	}
+
+	cases := []struct {
+		name string
+		obj  any
+	}{
+		{"duplicate", &duplicate{}},
+		{"position", &position{}},
+		{"option", &option{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := Encode(NewBuffer(nil), c.obj)
+			Assert(t, true, errors.Is(err, ErrInvalidTag))
+
+			err = Decode(NewBuffer(make([]byte, 8)), c.obj)
+			Assert(t, true, errors.Is(err, ErrInvalidTag))
+		})
+	}
+}
+
+type wallet struct {
+	Owner   string
+	balance uint64
+	history []uint64
+	labels  map[string]string
+	fee     Amount
+	next    *wallet
+}
+
+func TestUnexportedFields(t *testing.T) {
+	in := wallet{
+		Owner:   "alice",
+		balance: 100,
+		history: []uint64{1, 2, 3},
+		labels:  map[string]string{"a": "b"},
+		fee:     newAmount("-5"),
+		next:    &wallet{Owner: "bob", balance: 1, fee: newAmount("0")},
+	}
+
+	t.Run("skipped", func(t *testing.T) {
+		buf := NewBuffer(nil)
+		err := Encode(buf, &in)
+		AssertEqual(t, nil, err)
+
+		// Only Owner is encoded.
+		AssertEqual(t, 4+len(in.Owner), buf.Len())
+
+		out := wallet{balance: 7}
+		err = Decode(buf, &out)
+		AssertEqual(t, nil, err)
+
+		AssertEqual(t, wallet{Owner: "alice", balance: 7}, out)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		out := wallet{}
+
+		buf := NewBuffer(nil)
+		buf.SetUnexported(true)
+
+		err := Encode(buf, &in)
+		AssertEqual(t, nil, err)
+		err = Decode(buf, &out)
+		AssertEqual(t, nil, err)
+
+		AssertEqual(t, in, out)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		out := wallet{}
+		w := &bytes.Buffer{}
+
+		enc := NewEncoder(w)
+		enc.SetUnexported(true)
+		err := enc.Encode(&in)
+		AssertEqual(t, nil, err)
+		err = enc.Flush()
+		AssertEqual(t, nil, err)
+
+		dec := NewDecoder(w)
+		dec.SetUnexported(true)
+		err = dec.Decode(&out)
+		AssertEqual(t, nil, err)
+
+		AssertEqual(t, in, out)
+	})
+}
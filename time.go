@@ -0,0 +1,69 @@
+package bitbox
+
+import (
+	"reflect"
+	"time"
+)
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// Encode time as unix seconds, nanoseconds and optional zone offset.
+// UTC times have no offset. Zone names and monotonic clock are not encoded.
+func encodeTime(buf *Buffer, t *time.Time) {
+	sec := t.Unix()
+	nsec := int32(t.Nanosecond())
+	writeFixed(buf, &sec)
+	writeFixed(buf, &nsec)
+
+	hasZone := uint8(0)
+
+	if t.Location() == time.UTC {
+		writeFixed(buf, &hasZone)
+		return
+	}
+
+	_, offset := t.Zone()
+
+	hasZone = 1
+	zone := int32(offset)
+
+	writeFixed(buf, &hasZone)
+	writeFixed(buf, &zone)
+}
+
+// Decode time, times with zone offset are decoded in fixed zone.
+func decodeTime(buf *Buffer, t *time.Time) error {
+	sec := int64(0)
+	nsec := int32(0)
+	hasZone := uint8(0)
+
+	err := readFixed(buf, &sec)
+	if err != nil {
+		return err
+	}
+
+	err = readFixed(buf, &nsec)
+	if err != nil {
+		return err
+	}
+
+	err = readFixed(buf, &hasZone)
+	if err != nil {
+		return err
+	}
+
+	if hasZone == 0 {
+		*t = time.Unix(sec, int64(nsec)).UTC()
+		return nil
+	}
+
+	zone := int32(0)
+
+	err = readFixed(buf, &zone)
+	if err != nil {
+		return err
+	}
+
+	*t = time.Unix(sec, int64(nsec)).In(time.FixedZone("", int(zone)))
+	return nil
+}
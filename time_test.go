@@ -0,0 +1,75 @@
+package bitbox
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTime(t *testing.T) {
+	type event struct {
+		Name    string
+		At      time.Time
+		Timeout time.Duration
+		Seen    []time.Time
+	}
+
+	utc := time.Date(2024, 5, 17, 10, 30, 15, 123456789, time.UTC)
+
+	runTest(t, "utc", utc)
+	runTest(t, "zero", time.Time{})
+	runTest(t, "before_epoch", time.Date(1901, 1, 1, 0, 0, 0, 1, time.UTC))
+	runTest(t, "duration", 90*time.Second+5)
+	runTest(t, "negative_duration", -time.Hour)
+	runTest(t, "slice", []time.Time{utc, {}, utc.Add(time.Nanosecond)})
+	runTest(t, "durations", []time.Duration{time.Millisecond, -1, 0})
+	runTest(t, "array", [2]time.Time{utc, utc.Add(time.Hour)})
+	runTest(t, "map", map[string]time.Time{"start": utc})
+	runTest(t, "struct", event{"deploy", utc, time.Minute, []time.Time{utc}})
+
+	t.Run("zone", func(t *testing.T) {
+		in := time.Date(2024, 5, 17, 12, 30, 15, 0, time.FixedZone("CEST", 2*60*60))
+		out := time.Time{}
+
+		buf := NewBuffer(nil)
+		err := Encode(buf, in)
+		AssertEqual(t, nil, err)
+		err = Decode(buf, &out)
+		AssertEqual(t, nil, err)
+
+		_, offset := out.Zone()
+
+		Assert(t, true, in.Equal(out))
+		AssertEqual(t, 2*60*60, offset)
+		AssertEqual(t, in.Hour(), out.Hour())
+	})
+
+	t.Run("monotonic", func(t *testing.T) {
+		in := time.Now()
+		out := time.Time{}
+
+		buf := NewBuffer(nil)
+		err := Encode(buf, &in)
+		AssertEqual(t, nil, err)
+		err = Decode(buf, &out)
+		AssertEqual(t, nil, err)
+
+		Assert(t, true, in.Equal(out))
+	})
+
+	t.Run("size", func(t *testing.T) {
+		buf := NewBuffer(nil)
+		err := Encode(buf, utc, time.Second)
+		AssertEqual(t, nil, err)
+		AssertEqual(t, 13+8, buf.Len())
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		buf := NewBuffer(nil)
+		Encode(buf, utc)
+
+		out := time.Time{}
+		err := Decode(NewBuffer(buf.Data()[:10]), &out)
+		Assert(t, true, errors.Is(err, ErrOutOfBounds))
+	})
+}